@@ -0,0 +1,194 @@
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType:   reflect.TypeOf(map[interface{}]interface{}(nil)),
+	MaxNestedLevels:  32,
+	MaxArrayElements: 65536,
+	MaxMapPairs:      65536,
+}.DecMode()
+
+var cborEncMode, _ = cbor.CoreDetEncOptions().EncMode()
+
+// metadata中的cbor转换成json
+func Cbor2json(cborData []byte) ([]byte, error) {
+	if cborData == nil {
+		return nil, fmt.Errorf("no data")
+	}
+	value, err := DecodeCborMetadata(cborData)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// 解码成可以转换成json的值：
+// byte string是hex字符串，tag只保留内容，map的key转换成字符串，
+// 转换后相同的key保留字符串类型的key，然后按照key的cbor编码排序，保留第一个
+func DecodeCborMetadata(cborData []byte) (interface{}, error) {
+	var value interface{}
+	err := cborDecMode.Unmarshal(cborData, &value)
+	if err != nil {
+		return nil, err
+	}
+	return toJsonValue(value)
+}
+
+func toJsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, string, uint64, int64:
+		return v, nil
+	case float32:
+		return toJsonValue(float64(v))
+	case float64:
+		// json中没有NaN和Inf
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, nil
+		}
+		return v, nil
+	case big.Int:
+		return &v, nil
+	case []byte:
+		return hex.EncodeToString(v), nil
+	case cbor.ByteString:
+		// map的key是byte string时
+		return hex.EncodeToString([]byte(v)), nil
+	case cbor.Tag:
+		return toJsonValue(v.Content)
+	case cbor.RawTag:
+		var content interface{}
+		err := cborDecMode.Unmarshal(v.Content, &content)
+		if err != nil {
+			return nil, err
+		}
+		return toJsonValue(content)
+	case cbor.SimpleValue:
+		return nil, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := toJsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	case map[interface{}]interface{}:
+		return toJsonMap(v)
+	default:
+		// time.Time等可以直接转换成json的类型
+		return v, nil
+	}
+}
+
+type cborMapEntry struct {
+	key      string
+	isString bool
+	encoded  []byte
+	value    interface{}
+}
+
+func toJsonMap(m map[interface{}]interface{}) (map[string]interface{}, error) {
+	entries := make([]*cborMapEntry, 0, len(m))
+	for k, v := range m {
+		key, err := toJsonValue(k)
+		if err != nil {
+			return nil, err
+		}
+		entry := &cborMapEntry{value: v}
+		switch key := key.(type) {
+		case string:
+			entry.key = key
+			_, entry.isString = k.(string)
+		case nil:
+			entry.key = "null"
+		default:
+			encoded, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			entry.key = string(encoded)
+		}
+		entry.encoded, err = cborEncMode.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].isString != entries[j].isString {
+			return entries[i].isString
+		}
+		return bytes.Compare(entries[i].encoded, entries[j].encoded) < 0
+	})
+
+	result := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		if _, ok := result[entry.key]; ok {
+			continue
+		}
+		value, err := toJsonValue(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		result[entry.key] = value
+	}
+	return result, nil
+}
+
+// json转换成确定性编码的cbor，整数使用cbor的整数类型
+func Json2cbor(jsonData []byte) ([]byte, error) {
+	if jsonData == nil {
+		return nil, fmt.Errorf("no data")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(fromJsonValue(value))
+}
+
+func fromJsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, ok := new(big.Int).SetString(v.String(), 10); ok {
+			if u.IsUint64() {
+				return u.Uint64()
+			}
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJsonValue(item)
+		}
+		return v
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = fromJsonValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
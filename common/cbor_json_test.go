@@ -0,0 +1,109 @@
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// json转换成cbor再转换回来，内容不变
+func TestJson2cborRoundTrip(t *testing.T) {
+	cases := []string{
+		`{"a":1,"b":[1.5,-2,"x",true,false,null],"c":{"d":{}}}`,
+		`[]`,
+		`"text"`,
+		`18446744073709551615`,
+		// 超出uint64和int64的整数使用bignum
+		`{"big":18446744073709551616,"neg":-18446744073709551617}`,
+	}
+	for _, c := range cases {
+		data, err := Json2cbor([]byte(c))
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		result, err := Cbor2json(data)
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		if string(result) != c {
+			t.Errorf("%s round trip to %s", c, result)
+		}
+	}
+}
+
+func TestJson2cborEncoding(t *testing.T) {
+	cases := []struct {
+		json string
+		cbor string
+	}{
+		{`1`, "01"},
+		{`-1`, "20"},
+		{`1.5`, "f93e00"},
+		{`18446744073709551615`, "1bffffffffffffffff"},
+		{`18446744073709551616`, "c249010000000000000000"},
+		// 确定性编码，key按照编码排序
+		{`{"bb":1,"a":2}`, "a2616102626262" + "01"},
+	}
+	for _, c := range cases {
+		data, err := Json2cbor([]byte(c.json))
+		if err != nil {
+			t.Fatalf("%s: %v", c.json, err)
+		}
+		if hex.EncodeToString(data) != c.cbor {
+			t.Errorf("%s encoded to %x, expected %s", c.json, data, c.cbor)
+		}
+	}
+	if _, err := Json2cbor([]byte(`{`)); err == nil {
+		t.Errorf("invalid json accepted")
+	}
+}
+
+func TestCbor2json(t *testing.T) {
+	encode := func(value interface{}) []byte {
+		data, err := cbor.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	fromHex := func(s string) []byte {
+		data, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	cases := []struct {
+		name string
+		cbor []byte
+		json string
+	}{
+		{"integer keys", encode(map[interface{}]interface{}{1: "a", -1: "b", uint64(2): "c"}), `{"-1":"b","1":"a","2":"c"}`},
+		// 转换后相同的key保留字符串类型的key
+		{"duplicate keys", encode(map[interface{}]interface{}{1: "int", "1": "string"}), `{"1":"string"}`},
+		{"duplicate non-string keys", fromHex("a3f564626f6f6c4474727565656279746573617801"), `{"74727565":"bytes","true":"bool","x":1}`},
+		{"byte strings", encode(map[string]interface{}{"b": []byte{0xde, 0xad}, "e": []byte{}}), `{"b":"dead","e":""}`},
+		{"tag", encode(cbor.Tag{Number: 40000, Content: []interface{}{"x", 1}}), `["x",1]`},
+		{"bignum", fromHex("c249010000000000000000"), `18446744073709551616`},
+		{"negative bignum", fromHex("c349010000000000000000"), `-18446744073709551617`},
+		// NaN，Inf，-Inf
+		{"nan and inf", fromHex("83f97e00f97c00f9fc00"), `[null,null,null]`},
+		{"float64 nan", fromHex("81fb7ff8000000000000"), `[null]`},
+		{"simple value", fromHex("81f0"), `[null]`},
+	}
+	for _, c := range cases {
+		result, err := Cbor2json(c.cbor)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !bytes.Equal(result, []byte(c.json)) {
+			t.Errorf("%s: %s, expected %s", c.name, result, c.json)
+		}
+	}
+	if _, err := Cbor2json(nil); err == nil {
+		t.Errorf("nil data accepted")
+	}
+}
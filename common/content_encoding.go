@@ -0,0 +1,50 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	CONTENT_ENCODING_IDENTITY = "identity"
+	CONTENT_ENCODING_BROTLI   = "br"
+	CONTENT_ENCODING_GZIP     = "gzip"
+)
+
+// 解压后的最大长度，防止压缩炸弹
+const MAX_DECODED_CONTENT_SIZE = 4 * 1024 * 1024
+
+// 按照content-encoding解码，没有编码时直接返回content
+func DecodeContent(content []byte, encoding []byte) ([]byte, error) {
+	var reader io.Reader
+	name := strings.ToLower(strings.TrimSpace(string(encoding)))
+	switch name {
+	case "", CONTENT_ENCODING_IDENTITY:
+		return content, nil
+	case CONTENT_ENCODING_BROTLI:
+		reader = brotli.NewReader(bytes.NewReader(content))
+	case CONTENT_ENCODING_GZIP:
+		r, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		reader = r
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %s", name)
+	}
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, MAX_DECODED_CONTENT_SIZE+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > MAX_DECODED_CONTENT_SIZE {
+		return nil, fmt.Errorf("decoded content exceeds %d bytes", MAX_DECODED_CONTENT_SIZE)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,51 @@
+package common
+
+// ord的诅咒类型，jubilee之前被诅咒的铭文使用负数编号
+const (
+	CURSE_NONE                    = ""
+	CURSE_UNRECOGNIZED_EVEN_FIELD = "unrecognized-even-field"
+	CURSE_DUPLICATE_FIELD         = "duplicate-field"
+	CURSE_INCOMPLETE_FIELD        = "incomplete-field"
+	CURSE_NOT_IN_FIRST_INPUT      = "not-in-first-input"
+	CURSE_NOT_AT_OFFSET_ZERO      = "not-at-offset-zero"
+	CURSE_POINTER                 = "pointer"
+	CURSE_PUSHNUM                 = "pushnum"
+	CURSE_STUTTER                 = "stutter"
+	CURSE_REINSCRIPTION           = "reinscription"
+)
+
+// 铭文所在的聪上已有的铭文
+type SatInscriptions struct {
+	Count int
+	// 第一个铭文被诅咒或者在jubilee之后本应被诅咒
+	FirstCursedOrVindicated bool
+}
+
+// 按照ord的顺序检查诅咒，sat是铭文所在聪上已有的铭文，没有时为nil。
+// 调用者不知道聪上已有的铭文时也传nil，这时不会检查reinscription
+func GetCurse(inscription *Inscription, inputIndex int, sat *SatInscriptions) string {
+	switch {
+	case inscription.UnrecognizedEvenField:
+		return CURSE_UNRECOGNIZED_EVEN_FIELD
+	case inscription.DuplicateField:
+		return CURSE_DUPLICATE_FIELD
+	case inscription.IncompleteField:
+		return CURSE_INCOMPLETE_FIELD
+	case inputIndex != 0:
+		return CURSE_NOT_IN_FIRST_INPUT
+	case inscription.Offset != 0:
+		return CURSE_NOT_AT_OFFSET_ZERO
+	case inscription.Pointer != nil:
+		return CURSE_POINTER
+	case inscription.Pushnum:
+		return CURSE_PUSHNUM
+	case inscription.Stutter:
+		return CURSE_STUTTER
+	case sat != nil && sat.Count > 0:
+		// 第一个铭文被诅咒时，第二个铭文不算重复铭刻
+		if sat.Count > 1 || !sat.FirstCursedOrVindicated {
+			return CURSE_REINSCRIPTION
+		}
+	}
+	return CURSE_NONE
+}
@@ -0,0 +1,37 @@
+package common
+
+import "testing"
+
+func TestGetCurse(t *testing.T) {
+	cases := []struct {
+		name        string
+		inscription *Inscription
+		inputIndex  int
+		sat         *SatInscriptions
+		curse       string
+	}{
+		{"blessed", &Inscription{}, 0, nil, CURSE_NONE},
+		{"unrecognized even field", &Inscription{UnrecognizedEvenField: true}, 0, nil, CURSE_UNRECOGNIZED_EVEN_FIELD},
+		{"duplicate field", &Inscription{DuplicateField: true}, 0, nil, CURSE_DUPLICATE_FIELD},
+		{"incomplete field", &Inscription{IncompleteField: true}, 0, nil, CURSE_INCOMPLETE_FIELD},
+		{"not in first input", &Inscription{}, 1, nil, CURSE_NOT_IN_FIRST_INPUT},
+		{"not at offset zero", &Inscription{Offset: 1}, 0, nil, CURSE_NOT_AT_OFFSET_ZERO},
+		{"pointer", &Inscription{Pointer: []byte{0}}, 0, nil, CURSE_POINTER},
+		{"pushnum", &Inscription{Pushnum: true}, 0, nil, CURSE_PUSHNUM},
+		{"stutter", &Inscription{Stutter: true}, 0, nil, CURSE_STUTTER},
+		{"reinscription", &Inscription{}, 0, &SatInscriptions{Count: 1}, CURSE_REINSCRIPTION},
+		// 第一个铭文被诅咒时，第二个铭文不算重复铭刻，第三个开始都是
+		{"second after cursed", &Inscription{}, 0, &SatInscriptions{Count: 1, FirstCursedOrVindicated: true}, CURSE_NONE},
+		{"third after cursed", &Inscription{}, 0, &SatInscriptions{Count: 2, FirstCursedOrVindicated: true}, CURSE_REINSCRIPTION},
+		{"empty sat", &Inscription{}, 0, &SatInscriptions{}, CURSE_NONE},
+		// 按照ord的顺序，前面的诅咒优先
+		{"even field before input", &Inscription{UnrecognizedEvenField: true, DuplicateField: true}, 1, nil, CURSE_UNRECOGNIZED_EVEN_FIELD},
+		{"input before offset", &Inscription{Offset: 1, Pointer: []byte{0}}, 1, nil, CURSE_NOT_IN_FIRST_INPUT},
+		{"pointer before reinscription", &Inscription{Pointer: []byte{0}}, 0, &SatInscriptions{Count: 1}, CURSE_POINTER},
+	}
+	for _, c := range cases {
+		if curse := GetCurse(c.inscription, c.inputIndex, c.sat); curse != c.curse {
+			t.Errorf("%s: %q, expected %q", c.name, curse, c.curse)
+		}
+	}
+}
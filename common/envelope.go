@@ -0,0 +1,376 @@
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// 信封的标记 OP_FALSE OP_IF "ord"
+var ENVELOPE_PROTOCOL_ID = []byte("ord")
+
+const TAPROOT_ANNEX_PREFIX = 0x50
+
+const (
+	FIELD_RUNE = 13
+)
+
+// 未识别的tag，Tag可能不止一个字节
+type InscriptionField struct {
+	Tag   []byte
+	Value []byte
+}
+
+// 按照ord的信封规则解析出来的铭文
+type Inscription struct {
+	Body            []byte // 没有body时为nil，空的body不是nil
+	Content         []byte // 按照ContentEncoding解码后的Body，不能解码时为nil
+	DecodeError     error
+	ContentType     []byte
+	ContentEncoding []byte
+	Pointer         []byte
+	Parents         [][]byte
+	Delegate        []byte
+	Metadata        []byte // 分段的metadata已经拼接
+	Metaprotocol    []byte
+	Rune            []byte
+	// 没有识别的tag，包括重复tag中没有使用的值，按出现的顺序
+	Unrecognized []*InscriptionField
+
+	UnrecognizedEvenField bool
+	DuplicateField        bool
+	IncompleteField       bool // tag没有对应的值
+	Pushnum               bool // 使用了OP_1NEGATE，OP_1-OP_16
+	Stutter               bool // 前面有一个不完整的信封头，可能隔着其他信封
+	Offset                int  // 在同一个输入中的序号
+}
+
+// 转换成以tag为key的字段，重复的字段只保留第一个。
+// 用于识别协议，内容已经解码，所以没有FIELD_CONTENT_ENCODING，原始数据在Body中
+func (p *Inscription) Fields() map[int][]byte {
+	fields := make(map[int][]byte)
+	if p.Content != nil {
+		fields[FIELD_CONTENT] = p.Content
+	}
+	set := func(tag int, value []byte) {
+		if value != nil {
+			fields[tag] = value
+		}
+	}
+	set(FIELD_CONTENT_TYPE, p.ContentType)
+	set(FIELD_POINT, p.Pointer)
+	if len(p.Parents) > 0 {
+		set(FIELD_PARENT, p.Parents[0])
+	}
+	set(FIELD_META_DATA, p.Metadata)
+	set(FIELD_META_PROTOCOL, p.Metaprotocol)
+	set(FIELD_DELEGATE, p.Delegate)
+	set(FIELD_RUNE, p.Rune)
+	return fields
+}
+
+// pointer是小端序的聪的偏移，超过8字节的部分必须为0
+func (p *Inscription) GetPointer() (uint64, bool) {
+	if p.Pointer == nil {
+		return 0, false
+	}
+	for i := 8; i < len(p.Pointer); i++ {
+		if p.Pointer[i] != 0 {
+			return 0, false
+		}
+	}
+	var pointer uint64
+	for i := 0; i < len(p.Pointer) && i < 8; i++ {
+		pointer |= uint64(p.Pointer[i]) << (8 * i)
+	}
+	return pointer, true
+}
+
+// 所有格式正确的parent铭文id
+func (p *Inscription) GetParents() []string {
+	result := make([]string, 0, len(p.Parents))
+	for _, value := range p.Parents {
+		id, ok := ParseInscriptionId(value)
+		if ok {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// 格式正确的delegate铭文id
+func (p *Inscription) GetDelegate() (string, bool) {
+	if p.Delegate == nil {
+		return "", false
+	}
+	return ParseInscriptionId(p.Delegate)
+}
+
+// 二进制形式：32字节txid(字节序和显示的相反)，后面是小端序的index，省略末尾的0
+func ParseInscriptionId(value []byte) (string, bool) {
+	if len(value) < 32 || len(value) > 32+4 {
+		return "", false
+	}
+	rest := value[32:]
+	if len(rest) > 0 && rest[len(rest)-1] == 0 {
+		return "", false
+	}
+	txid := make([]byte, 32)
+	for i := 0; i < 32; i++ {
+		txid[i] = value[31-i]
+	}
+	var index uint32
+	for i, b := range rest {
+		index |= uint32(b) << (8 * i)
+	}
+	return fmt.Sprintf("%si%d", hex.EncodeToString(txid), index), true
+}
+
+// 只有script path花费时才有tapscript：倒数第二项，有annex时是倒数第三项
+func GetTapscript(witness [][]byte) []byte {
+	n := len(witness)
+	if n == 0 {
+		return nil
+	}
+	last := witness[n-1]
+	pos := 2
+	if n >= 2 && len(last) > 0 && last[0] == TAPROOT_ANNEX_PREFIX {
+		pos = 3
+	}
+	if n < pos {
+		return nil
+	}
+	return witness[n-pos]
+}
+
+// 解析输入中的所有铭文，只解析tapscript，脚本格式错误时返回错误
+func ParseEnvelopes(witness [][]byte) ([]*Inscription, error) {
+	script := GetTapscript(witness)
+	if script == nil {
+		return nil, nil
+	}
+
+	result := make([]*Inscription, 0)
+	tokenizer := newEnvelopeTokenizer(script)
+	stuttered := false
+	for tokenizer.next() {
+		if !tokenizer.isEmptyPush() {
+			continue
+		}
+		stutter, payload, ok := readEnvelope(tokenizer)
+		if ok {
+			inscription := newInscription(payload)
+			inscription.Pushnum = payload.pushnum
+			inscription.Stutter = stuttered
+			inscription.Offset = len(result)
+			result = append(result, inscription)
+		} else {
+			// 和ord一样只在没有解析出信封时更新，之后的信封都带有这个标记
+			stuttered = stutter
+		}
+	}
+	if err := tokenizer.err(); err != nil {
+		return nil, fmt.Errorf("invalid tapscript: %v", err)
+	}
+	return result, nil
+}
+
+type envelopePayload struct {
+	pushes  [][]byte
+	pushnum bool
+}
+
+// 已经读到OP_FALSE，不是完整的信封时返回下一个指令是否是OP_FALSE
+func readEnvelope(t *envelopeTokenizer) (bool, *envelopePayload, bool) {
+	if !t.accept(func() bool { return t.opcode() == txscript.OP_IF }) {
+		return t.peekEmptyPush(), nil, false
+	}
+	if !t.accept(func() bool { return t.isPush() && bytes.Equal(t.data(), ENVELOPE_PROTOCOL_ID) }) {
+		return t.peekEmptyPush(), nil, false
+	}
+
+	payload := &envelopePayload{pushes: make([][]byte, 0)}
+	for t.next() {
+		opcode := t.opcode()
+		switch {
+		case opcode == txscript.OP_ENDIF:
+			return false, payload, true
+		case opcode == txscript.OP_1NEGATE:
+			payload.pushnum = true
+			payload.pushes = append(payload.pushes, []byte{0x81})
+		case opcode >= txscript.OP_1 && opcode <= txscript.OP_16:
+			payload.pushnum = true
+			payload.pushes = append(payload.pushes, []byte{opcode - txscript.OP_1 + 1})
+		case t.isPush():
+			payload.pushes = append(payload.pushes, t.data())
+		default:
+			return false, nil, false
+		}
+	}
+	// 没有OP_ENDIF
+	return false, nil, false
+}
+
+// 第一个偶数位置的空数据后面都是body，前面是tag和值
+func newInscription(payload *envelopePayload) *Inscription {
+	pushes := payload.pushes
+	bodyIndex := -1
+	for i := 0; i < len(pushes); i += 2 {
+		if len(pushes[i]) == 0 {
+			bodyIndex = i
+			break
+		}
+	}
+
+	inscription := &Inscription{}
+	fieldEnd := len(pushes)
+	if bodyIndex >= 0 {
+		fieldEnd = bodyIndex
+		body := make([]byte, 0)
+		for _, push := range pushes[bodyIndex+1:] {
+			body = append(body, push...)
+		}
+		inscription.Body = body
+	}
+
+	fields := make([]*InscriptionField, 0)
+	count := make(map[string]int)
+	for i := 0; i < fieldEnd; i += 2 {
+		if i+1 >= fieldEnd {
+			inscription.IncompleteField = true
+			break
+		}
+		fields = append(fields, &InscriptionField{Tag: pushes[i], Value: pushes[i+1]})
+		count[string(pushes[i])]++
+	}
+	for _, n := range count {
+		if n > 1 {
+			inscription.DuplicateField = true
+		}
+	}
+
+	used := make(map[*InscriptionField]bool)
+	// 只使用第一个值
+	take := func(tag byte) []byte {
+		for _, field := range fields {
+			if len(field.Tag) == 1 && field.Tag[0] == tag {
+				used[field] = true
+				return field.Value
+			}
+		}
+		return nil
+	}
+	// 使用所有的值
+	takeAll := func(tag byte) [][]byte {
+		var values [][]byte
+		for _, field := range fields {
+			if len(field.Tag) == 1 && field.Tag[0] == tag {
+				used[field] = true
+				values = append(values, field.Value)
+			}
+		}
+		return values
+	}
+
+	inscription.ContentType = take(FIELD_CONTENT_TYPE)
+	inscription.Pointer = take(FIELD_POINT)
+	inscription.Parents = takeAll(FIELD_PARENT)
+	if chunks := takeAll(FIELD_META_DATA); chunks != nil {
+		inscription.Metadata = bytes.Join(chunks, nil)
+	}
+	inscription.Metaprotocol = take(FIELD_META_PROTOCOL)
+	inscription.ContentEncoding = take(FIELD_CONTENT_ENCODING)
+	inscription.Delegate = take(FIELD_DELEGATE)
+	inscription.Rune = take(FIELD_RUNE)
+	if inscription.Body != nil {
+		inscription.Content, inscription.DecodeError = DecodeContent(inscription.Body, inscription.ContentEncoding)
+	}
+
+	for _, field := range fields {
+		if used[field] {
+			continue
+		}
+		inscription.Unrecognized = append(inscription.Unrecognized, field)
+		if len(field.Tag) > 0 && field.Tag[0]%2 == 0 {
+			inscription.UnrecognizedEvenField = true
+		}
+	}
+	return inscription
+}
+
+// 可以预读一个指令的tokenizer
+type envelopeTokenizer struct {
+	tokenizer txscript.ScriptTokenizer
+	peeked    bool
+	peekOk    bool
+	curOpcode byte
+	curData   []byte
+}
+
+func newEnvelopeTokenizer(script []byte) *envelopeTokenizer {
+	return &envelopeTokenizer{tokenizer: txscript.MakeScriptTokenizer(0, script)}
+}
+
+func (t *envelopeTokenizer) next() bool {
+	if t.peeked {
+		t.peeked = false
+		return t.peekOk
+	}
+	if !t.tokenizer.Next() {
+		return false
+	}
+	t.curOpcode = t.tokenizer.Opcode()
+	t.curData = t.tokenizer.Data()
+	return true
+}
+
+// 下一个指令符合条件时读取，否则留给下次读取
+func (t *envelopeTokenizer) accept(match func() bool) bool {
+	if !t.peek() {
+		return false
+	}
+	if match() {
+		t.peeked = false
+		return true
+	}
+	return false
+}
+
+// 读取下一个指令，下一次next返回同一个指令
+func (t *envelopeTokenizer) peek() bool {
+	if !t.peeked {
+		t.peekOk = t.next()
+		t.peeked = true
+	}
+	return t.peekOk
+}
+
+func (t *envelopeTokenizer) peekEmptyPush() bool {
+	return t.peek() && t.isEmptyPush()
+}
+
+func (t *envelopeTokenizer) opcode() byte {
+	return t.curOpcode
+}
+
+func (t *envelopeTokenizer) data() []byte {
+	if t.curData == nil {
+		return []byte{}
+	}
+	return t.curData
+}
+
+// OP_0和直接压入数据的指令，不包括OP_1NEGATE和OP_1-OP_16
+func (t *envelopeTokenizer) isPush() bool {
+	return t.curOpcode <= txscript.OP_PUSHDATA4
+}
+
+func (t *envelopeTokenizer) isEmptyPush() bool {
+	return t.isPush() && len(t.curData) == 0
+}
+
+func (t *envelopeTokenizer) err() error {
+	return t.tokenizer.Err()
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+var controlBlock = append([]byte{0xc0}, make([]byte, 32)...)
+
+func envelopeSeed(t testing.TB, build func(b *txscript.ScriptBuilder)) []byte {
+	b := txscript.NewScriptBuilder()
+	b.AddData(make([]byte, 32)).AddOp(txscript.OP_CHECKSIG)
+	b.AddOp(txscript.OP_FALSE).AddOp(txscript.OP_IF).AddData(ENVELOPE_PROTOCOL_ID)
+	build(b)
+	b.AddOp(txscript.OP_ENDIF)
+	script, err := b.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func addEnvelopeSeeds(f *testing.F) {
+	f.Add(envelopeSeed(f, func(b *txscript.ScriptBuilder) {
+		b.AddData([]byte{FIELD_CONTENT_TYPE}).AddData([]byte("text/plain"))
+		b.AddOp(txscript.OP_0).AddData([]byte("hello"))
+	}))
+	f.Add(envelopeSeed(f, func(b *txscript.ScriptBuilder) {
+		b.AddData([]byte{FIELD_POINT}).AddData([]byte{1, 2})
+		b.AddData([]byte{FIELD_META_DATA}).AddData([]byte{0xa0})
+		b.AddOp(txscript.OP_1NEGATE)
+	}))
+	// 不完整的 PUSHDATA2 和 PUSHDATA4
+	f.Add([]byte{txscript.OP_0, txscript.OP_IF, 3, 'o', 'r', 'd', txscript.OP_PUSHDATA2, 0xff})
+	f.Add([]byte{txscript.OP_0, txscript.OP_IF, 3, 'o', 'r', 'd', txscript.OP_PUSHDATA4, 0xff, 0xff, 0xff, 0xff, 1})
+	f.Add([]byte{txscript.OP_0, txscript.OP_0, txscript.OP_IF, 3, 'o', 'r', 'd'})
+}
+
+func checkInscriptions(t *testing.T, inscriptions []*Inscription, err error) {
+	if err != nil {
+		if inscriptions != nil {
+			t.Fatalf("inscriptions returned with error %v", err)
+		}
+		return
+	}
+	for i, inscription := range inscriptions {
+		if inscription.Offset != i {
+			t.Fatalf("inscription %d has offset %d", i, inscription.Offset)
+		}
+		fields := inscription.Fields()
+		if content, ok := fields[FIELD_CONTENT]; ok && !bytes.Equal(content, inscription.Content) {
+			t.Fatalf("inscription %d content mismatch", i)
+		}
+		if inscription.Body == nil && inscription.Content != nil {
+			t.Fatalf("inscription %d has content without body", i)
+		}
+		inscription.GetPointer()
+		inscription.GetParents()
+		inscription.GetDelegate()
+	}
+}
+
+func FuzzParseEnvelopes(f *testing.F) {
+	addEnvelopeSeeds(f)
+	f.Fuzz(func(t *testing.T, script []byte) {
+		inscriptions, err := ParseEnvelopes([][]byte{script, controlBlock})
+		checkInscriptions(t, inscriptions, err)
+	})
+}
+
+// 任意的witness，每一项的第一个字节是长度
+func FuzzParseWitness(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{2, 0x50, 1})
+	f.Add([]byte{8, txscript.OP_0, txscript.OP_IF, 3, 'o', 'r', 'd', txscript.OP_ENDIF, 1, 0xc0, 2, 0x50, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		witness := make([][]byte, 0)
+		for len(data) > 0 {
+			n := int(data[0])
+			data = data[1:]
+			if n > len(data) {
+				n = len(data)
+			}
+			witness = append(witness, data[:n])
+			data = data[n:]
+		}
+		inscriptions, err := ParseEnvelopes(witness)
+		checkInscriptions(t, inscriptions, err)
+	})
+}
+
+func FuzzDecodeContent(f *testing.F) {
+	f.Add([]byte{0x1f, 0x8b}, []byte(CONTENT_ENCODING_GZIP))
+	f.Add([]byte{0x0b, 0x02, 0x80}, []byte(CONTENT_ENCODING_BROTLI))
+	f.Fuzz(func(t *testing.T, content []byte, encoding []byte) {
+		decoded, err := DecodeContent(content, encoding)
+		if err == nil && len(decoded) > MAX_DECODED_CONTENT_SIZE {
+			t.Fatalf("decoded %d bytes", len(decoded))
+		}
+	})
+}
+
+func FuzzCbor2json(f *testing.F) {
+	f.Add([]byte{0xa1, 0x61, 'p', 0x63, 's', 'n', 's'})
+	f.Add([]byte{0xa2, 0x01, 0x01, 0x61, '1', 0x02})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Cbor2json(data)
+	})
+}
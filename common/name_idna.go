@@ -0,0 +1,61 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+const IDNA_ACE_PREFIX = "xn--"
+
+// 名字转换成DNS中使用的ASCII形式，非ASCII的label转成 xn-- 开头的label
+// 使用IDNA2008的注册规则，名字需要是规范化以后的形式，不能表示为合法IDNA label的名字返回错误
+func NameToASCII(name string) (string, error) {
+	// idna包使用UTS46的表，emoji等符号在IDNA2008(RFC 5892)中是不允许的
+	for _, r := range name {
+		if r == '.' || r == '-' || r < unicode.MaxASCII {
+			continue
+		}
+		if !unicode.In(r, unicode.L, unicode.M, unicode.Nd) {
+			return "", fmt.Errorf("name %s can't be represented in IDNA, disallowed rune %U", name, r)
+		}
+	}
+	ascii, err := idna.Registration.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("name %s can't be represented in IDNA, %v", name, err)
+	}
+	// 必须能转换回原来的名字，否则DNS查询时找不到
+	back, err := idna.Registration.ToUnicode(ascii)
+	if err != nil || back != name {
+		return "", fmt.Errorf("name %s can't be represented in IDNA", name)
+	}
+	return ascii, nil
+}
+
+// DNS查询中的名字转换成unicode形式，允许末尾的'.'，大小写不敏感
+// 返回的名字还需要经过 NormalizeName 才能用于查找
+func NameFromASCII(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+	result, err := idna.Lookup.ToUnicode(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid IDNA name %s, %v", name, err)
+	}
+	// 只接受规范的A-label，比如 xn--zz- 解码后是ASCII的zz，不是合法的A-label
+	ascii, err := idna.Lookup.ToASCII(result)
+	if err != nil || ascii != strings.ToLower(name) {
+		return "", fmt.Errorf("invalid IDNA name %s", name)
+	}
+	return result, nil
+}
+
+// 包含 xn-- 开头的label
+func IsACEName(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if strings.HasPrefix(strings.ToLower(label), IDNA_ACE_PREFIX) {
+			return true
+		}
+	}
+	return false
+}
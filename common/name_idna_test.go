@@ -0,0 +1,84 @@
+package common
+
+import "testing"
+
+func TestNameToASCII(t *testing.T) {
+	cases := []struct {
+		name  string
+		ascii string // 为空时不能表示为IDNA
+	}{
+		{"abc.sats", "abc.sats"},
+		{"a--b.sats", "a--b.sats"},
+		{"bücher.sats", "xn--bcher-kva.sats"},
+		{"中文.sats", "xn--fiq228c.sats"},
+		// IDNA不检查混合的文字，形似的名字由confusable规则处理
+		{"pаypal.sats", "xn--pypal-4ve.sats"},
+		// 同一个label中混合从左到右和从右到左的文字
+		{"aا.sats", ""},
+		{"🍕.sats", ""},
+		// 没有规范化的名字
+		{"Bücher.sats", ""},
+		// 已经是ASCII形式的名字不能再转换
+		{"xn--bcher-kva.sats", ""},
+		{"-abc.sats", ""},
+	}
+	for _, c := range cases {
+		ascii, err := NameToASCII(c.name)
+		if c.ascii == "" {
+			if err == nil {
+				t.Errorf("%s converted to %s, expected error", c.name, ascii)
+			}
+			continue
+		}
+		if err != nil || ascii != c.ascii {
+			t.Errorf("%s: %s %v, expected %s", c.name, ascii, err, c.ascii)
+		}
+	}
+}
+
+func TestNameFromASCII(t *testing.T) {
+	cases := []struct {
+		name    string
+		unicode string // 为空时不是合法的IDNA名字
+	}{
+		{"abc.sats", "abc.sats"},
+		{"xn--bcher-kva.sats", "bücher.sats"},
+		// 大小写不敏感，允许末尾的'.'
+		{"XN--BCHER-KVA.SATS.", "bücher.sats"},
+		{"xn--55qx5d.sats", "公司.sats"},
+		{"xn--pypal-4ve.sats", "pаypal.sats"},
+		// 不能解码的punycode
+		{"xn--a.sats", ""},
+		{"xn--bcher-kva9999.sats", ""},
+		// 解码后是ASCII或者空的label
+		{"xn--zz-.sats", ""},
+		{"xn--.sats", ""},
+	}
+	for _, c := range cases {
+		unicode, err := NameFromASCII(c.name)
+		if c.unicode == "" {
+			if err == nil {
+				t.Errorf("%s converted to %s, expected error", c.name, unicode)
+			}
+			continue
+		}
+		if err != nil || unicode != c.unicode {
+			t.Errorf("%s: %s %v, expected %s", c.name, unicode, err, c.unicode)
+		}
+	}
+}
+
+func TestIsACEName(t *testing.T) {
+	cases := map[string]bool{
+		"xn--bcher-kva.sats": true,
+		"abc.XN--FIQ228C":    true,
+		"abc.sats":           false,
+		"axn--b.sats":        false,
+		"bücher.sats":        false,
+	}
+	for name, expected := range cases {
+		if IsACEName(name) != expected {
+			t.Errorf("IsACEName(%s) is %v", name, !expected)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// 名字长度的计算单位
+const (
+	NAME_LEN_BYTE     = "byte"
+	NAME_LEN_RUNE     = "rune"
+	NAME_LEN_GRAPHEME = "grapheme" // 用户看到的字符，比如带肤色的emoji算一个
+)
+
+// 没有单独配置规则的后缀
+const NAME_NAMESPACE_ANY = "*"
+
+// 从ActivationHeight开始生效，直到同一个namespace的下一条规则生效
+type NameLenRule struct {
+	Namespace        string `yaml:"namespace"` // 名字的后缀，空表示没有后缀的名字，*表示其他所有后缀
+	Unit             string `yaml:"unit"`
+	Min              int    `yaml:"min"`
+	Max              int    `yaml:"max"` // 包括后缀，0表示不限制
+	ActivationHeight int    `yaml:"activation_height"`
+}
+
+// 每个namespace按生效高度排序的长度规则，历史上的注册使用当时有效的规则
+type NameLenPolicy struct {
+	rules map[string][]*NameLenRule
+}
+
+// 和之前一样按字节计算：没有后缀的名字3-32字节，有后缀的名字最多32字节
+func NewNameLenPolicy() *NameLenPolicy {
+	p := &NameLenPolicy{rules: make(map[string][]*NameLenRule)}
+	p.AddRule(&NameLenRule{Namespace: "", Unit: NAME_LEN_BYTE, Min: MIN_NAME_LEN, Max: MAX_NAME_LEN})
+	p.AddRule(&NameLenRule{Namespace: NAME_NAMESPACE_ANY, Unit: NAME_LEN_BYTE, Min: 0, Max: MAX_NAME_LEN})
+	return p
+}
+
+var defaultNameLenPolicy = NewNameLenPolicy()
+
+// 同一个namespace在同一个高度的规则会被替换
+func (p *NameLenPolicy) AddRule(rule *NameLenRule) error {
+	if rule.Unit != NAME_LEN_BYTE && rule.Unit != NAME_LEN_RUNE && rule.Unit != NAME_LEN_GRAPHEME {
+		return fmt.Errorf("invalid name length unit %s", rule.Unit)
+	}
+	if rule.Min < 0 || rule.Max < 0 || (rule.Max > 0 && rule.Min > rule.Max) {
+		return fmt.Errorf("invalid name length range %d-%d", rule.Min, rule.Max)
+	}
+	if rule.ActivationHeight < 0 {
+		return fmt.Errorf("invalid activation height %d", rule.ActivationHeight)
+	}
+
+	namespace := strings.ToLower(rule.Namespace)
+	rules := p.rules[namespace]
+	for i, r := range rules {
+		if r.ActivationHeight == rule.ActivationHeight {
+			rules[i] = rule
+			return nil
+		}
+	}
+	rules = append(rules, rule)
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ActivationHeight < rules[j].ActivationHeight
+	})
+	p.rules[namespace] = rules
+	return nil
+}
+
+// 在height有效的规则。namespace自己的规则还没有生效时，使用当时有效的*规则，
+// 都没有时使用内置的默认规则，配置新的规则不会改变生效高度之前的结果
+func (p *NameLenPolicy) GetRule(namespace string, height int) *NameLenRule {
+	rule := p.activeRule(namespace, height)
+	if rule == nil && namespace != "" {
+		rule = p.activeRule(NAME_NAMESPACE_ANY, height)
+	}
+	if rule == nil && p != defaultNameLenPolicy {
+		rule = defaultNameLenPolicy.GetRule(namespace, height)
+	}
+	return rule
+}
+
+func (p *NameLenPolicy) activeRule(namespace string, height int) *NameLenRule {
+	var result *NameLenRule
+	for _, rule := range p.rules[namespace] {
+		if rule.ActivationHeight > height {
+			break
+		}
+		result = rule
+	}
+	return result
+}
+
+// 返回名字长度不符合规则的原因，符合规则时返回空
+func (p *NameLenPolicy) Check(name string, height int) string {
+	rule := p.GetRule(GetNameNamespace(name), height)
+	if rule == nil {
+		return ""
+	}
+	l := NameLen(name, rule.Unit)
+	if rule.Max > 0 && l > rule.Max {
+		return NAME_TOO_LONG
+	}
+	if l < rule.Min {
+		return NAME_TOO_SHORT
+	}
+	return ""
+}
+
+// 格式：namespace:unit:min:max:height，多条规则用','分隔，比如 ":rune:1:32:900000,*:rune:1:32:900000"
+func ParseNameLenRules(s string) ([]*NameLenRule, error) {
+	result := make([]*NameLenRule, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		fields := strings.Split(item, ":")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("invalid name length rule %s", item)
+		}
+		values := make([]int, 3)
+		for i, field := range fields[2:] {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid name length rule %s, %v", item, err)
+			}
+			values[i] = value
+		}
+		result = append(result, &NameLenRule{
+			Namespace:        fields[0],
+			Unit:             fields[1],
+			Min:              values[0],
+			Max:              values[1],
+			ActivationHeight: values[2],
+		})
+	}
+	return result, nil
+}
+
+// abc.btc 的namespace是btc，abc的namespace是空
+func GetNameNamespace(name string) string {
+	_, suffix, found := strings.Cut(name, ".")
+	if !found {
+		return ""
+	}
+	return suffix
+}
+
+func NameLen(name string, unit string) int {
+	switch unit {
+	case NAME_LEN_RUNE:
+		return utf8.RuneCountInString(name)
+	case NAME_LEN_GRAPHEME:
+		return GraphemeCount(name)
+	default:
+		return len(name)
+	}
+}
+
+// Unicode TR29 扩展字素簇的数量，比如带肤色的emoji、国旗和韩文音节都算一个
+func GraphemeCount(s string) int {
+	return uniseg.GraphemeClusterCount(s)
+}
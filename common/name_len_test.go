@@ -0,0 +1,94 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetRuleBeforeActivation(t *testing.T) {
+	policy := NewNameLenPolicy()
+	err := policy.AddRule(&NameLenRule{Namespace: "btc", Unit: NAME_LEN_RUNE, Min: 1, Max: 8, ActivationHeight: 900000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := strings.Repeat("a", 40) + ".btc"
+	medium := "abcdefghij.btc"
+	cases := []struct {
+		name   string
+		height int
+		reason string
+	}{
+		// 生效之前使用默认的*规则，最多32字节
+		{long, 899999, NAME_TOO_LONG},
+		{medium, 899999, ""},
+		{medium, 900000, NAME_TOO_LONG},
+		{"abc.btc", 900000, ""},
+		{long, 900000, NAME_TOO_LONG},
+		// 其他namespace不受影响
+		{strings.Repeat("a", 20) + ".sats", 900000, ""},
+	}
+	for _, c := range cases {
+		if reason := policy.Check(c.name, c.height); reason != c.reason {
+			t.Errorf("%s at %d: %q, expected %q", c.name, c.height, reason, c.reason)
+		}
+	}
+}
+
+func TestGetRuleFallbackToAny(t *testing.T) {
+	policy := NewNameLenPolicy()
+	policy.AddRule(&NameLenRule{Namespace: NAME_NAMESPACE_ANY, Unit: NAME_LEN_RUNE, Min: 1, Max: 12, ActivationHeight: 800000})
+	policy.AddRule(&NameLenRule{Namespace: "btc", Unit: NAME_LEN_RUNE, Min: 1, Max: 8, ActivationHeight: 900000})
+
+	if rule := policy.GetRule("btc", 799999); rule.Unit != NAME_LEN_BYTE || rule.Max != MAX_NAME_LEN {
+		t.Fatalf("btc rule at 799999 is %+v, expected the built-in * rule", rule)
+	}
+	if rule := policy.GetRule("btc", 850000); rule.Namespace != NAME_NAMESPACE_ANY || rule.Max != 12 {
+		t.Fatalf("btc rule at 850000 is %+v, expected the configured * rule", rule)
+	}
+	if rule := policy.GetRule("btc", 900000); rule.Namespace != "btc" {
+		t.Fatalf("btc rule at 900000 is %+v, expected its own rule", rule)
+	}
+	// 没有后缀的名字不使用*规则
+	if rule := policy.GetRule("", 850000); rule.Namespace != "" || rule.Min != MIN_NAME_LEN {
+		t.Fatalf("rule for names without suffix is %+v", rule)
+	}
+
+	// 没有任何规则时使用内置的默认规则
+	empty := &NameLenPolicy{rules: make(map[string][]*NameLenRule)}
+	if rule := empty.GetRule("", 0); rule == nil || rule.Min != MIN_NAME_LEN {
+		t.Fatalf("empty policy rule %+v, expected the built-in rule", rule)
+	}
+	if rule := empty.GetRule("btc", 0); rule == nil || rule.Max != MAX_NAME_LEN {
+		t.Fatalf("empty policy rule %+v, expected the built-in * rule", rule)
+	}
+}
+
+// 来自 Unicode GraphemeBreakTest.txt 的典型情况
+func TestGraphemeCount(t *testing.T) {
+	cases := []struct {
+		s     string
+		count int
+	}{
+		{"abc", 3},
+		{"\r\n", 1},
+		{"e\u0301", 1},              // 组合符号
+		{"\u0301a", 2},              // 开头的组合符号单独算一个
+		{"\U0001f44d\U0001f3fd", 1}, // 肤色修饰符
+		{"\U0001f468\u200d\U0001f469\u200d\U0001f467\u200d\U0001f466", 1},             // ZWJ连接的emoji
+		{"\U0001f3f3\ufe0f\u200d\U0001f308", 1},                                       // 变体选择符和ZWJ
+		{"a\u200db", 2},                                                               // ZWJ只连接emoji
+		{"\U0001f1fa\U0001f1f8\U0001f1ec\U0001f1e7", 2},                               // 区域指示符两两组成国旗
+		{"\U0001f1fa\U0001f1f8\U0001f1ec", 2},                                         // 落单的区域指示符
+		{"\U0001f3f4\U000e0067\U000e0062\U000e0065\U000e006e\U000e0067\U000e007f", 1}, // tag序列
+		{"\u1100\u1161\u11a8", 1},                                                     // 韩文字母组成一个音节
+		{"\ud55c\uad6d", 2},
+		{"\u1100\uac00", 1}, // L + LV
+		{"\u0e01\u0e33", 1}, // SpacingMark
+	}
+	for _, c := range cases {
+		if count := GraphemeCount(c.s); count != c.count {
+			t.Errorf("GraphemeCount(%+q) = %d, expected %d", c.s, count, c.count)
+		}
+	}
+}
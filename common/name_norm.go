@@ -0,0 +1,129 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 名字的unicode规范化方式
+const (
+	NORM_NONE = "none"
+	NORM_NFC  = "nfc"
+	NORM_NFKC = "nfkc"
+)
+
+// 对形似名字的处理方式
+const (
+	CONFUSABLE_OFF    = "off"
+	CONFUSABLE_FLAG   = "flag"   // 允许注册，但是标记出和哪个名字形似
+	CONFUSABLE_REJECT = "reject" // 不允许注册
+)
+
+// 规范化后再转小写，判断名字是否重复都基于这个结果
+func NormalizeName(name string, form string) string {
+	switch form {
+	case NORM_NFC:
+		return norm.NFC.String(strings.ToLower(norm.NFC.String(name)))
+	case NORM_NFKC:
+		return norm.NFKC.String(strings.ToLower(norm.NFKC.String(name)))
+	default:
+		return strings.ToLower(name)
+	}
+}
+
+func IsValidNormForm(form string) bool {
+	return form == NORM_NONE || form == NORM_NFC || form == NORM_NFKC
+}
+
+func IsValidConfusableMode(mode string) bool {
+	return mode == CONFUSABLE_OFF || mode == CONFUSABLE_FLAG || mode == CONFUSABLE_REJECT
+}
+
+// 形似字符表，参考 Unicode TR39 的 skeleton 算法
+type ConfusableTable struct {
+	mapping map[rune]string
+}
+
+// 内置常见的西里尔字母、希腊字母和数字与拉丁字母的混淆，名字已经转成小写
+var defaultConfusables = map[rune]string{
+	'а': "a", 'в': "b", 'е': "e", 'һ': "h", 'і': "i", 'ј': "j",
+	'к': "k", 'ӏ': "l", 'м': "m", 'н': "h", 'о': "o", 'р': "p", 'с': "c", 'ԛ': "q",
+	'ѕ': "s", 'т': "t", 'у': "y", 'ԝ': "w", 'х': "x", 'ԁ': "d", 'ɡ': "g", 'ь': "b",
+	'α': "a", 'β': "b", 'ε': "e", 'η': "n", 'ι': "i", 'κ': "k", 'ν': "v", 'ο': "o",
+	'ρ': "p", 'τ': "t", 'υ': "u", 'χ': "x", 'γ': "y", 'ω': "w",
+	'ı': "i", 'ℓ': "l", 'ǀ': "l",
+	'0': "o", '1': "l",
+}
+
+func NewConfusableTable() *ConfusableTable {
+	table := &ConfusableTable{mapping: make(map[rune]string, len(defaultConfusables))}
+	for k, v := range defaultConfusables {
+		table.mapping[k] = v
+	}
+	return table
+}
+
+// 读取 confusables.txt 格式的文件，加入或覆盖内置的映射
+// 每行格式：source ; target ; type # comment，source和target都是十六进制码点
+func (t *ConfusableTable) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))
+		if text == "" {
+			continue
+		}
+		parts := strings.Split(text, ";")
+		if len(parts) < 2 {
+			return fmt.Errorf("%s:%d invalid confusable line", path, line)
+		}
+		source, err := parseCodePoints(parts[0])
+		if err != nil || len(source) != 1 {
+			return fmt.Errorf("%s:%d invalid source %s", path, line, parts[0])
+		}
+		target, err := parseCodePoints(parts[1])
+		if err != nil || len(target) == 0 {
+			return fmt.Errorf("%s:%d invalid target %s", path, line, parts[1])
+		}
+		t.mapping[source[0]] = string(target)
+	}
+	return scanner.Err()
+}
+
+func parseCodePoints(s string) ([]rune, error) {
+	result := make([]rune, 0)
+	for _, field := range strings.Fields(s) {
+		cp, err := strconv.ParseUint(field, 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rune(cp))
+	}
+	return result, nil
+}
+
+// 形似的名字有相同的skeleton
+func (t *ConfusableTable) Skeleton(name string) string {
+	var builder strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		if target, ok := t.mapping[r]; ok {
+			builder.WriteString(target)
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(builder.String())
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+)
+
+// 从ActivationHeight开始不能注册，之前的注册不受影响
+type NameRestriction struct {
+	Name             string `yaml:"name" json:"name"`
+	Type             string `yaml:"type" json:"type"` // NAME_RESERVED: 品牌保护、协议保留，NAME_BLOCKED: 违法内容
+	Reason           string `yaml:"reason" json:"reason,omitempty"`
+	ActivationHeight int    `yaml:"activation_height" json:"activationHeight"`
+}
+
+type NameRestrictions struct {
+	names map[string]*NameRestriction
+}
+
+func NewNameRestrictions() *NameRestrictions {
+	return &NameRestrictions{names: make(map[string]*NameRestriction)}
+}
+
+// name需要是规范化以后的名字，同名的限制会被替换
+func (p *NameRestrictions) Add(restriction *NameRestriction) error {
+	if restriction.Name == "" {
+		return fmt.Errorf("empty restricted name")
+	}
+	if restriction.Type != NAME_RESERVED && restriction.Type != NAME_BLOCKED {
+		return fmt.Errorf("invalid restriction type %s for name %s", restriction.Type, restriction.Name)
+	}
+	if restriction.ActivationHeight < 0 {
+		return fmt.Errorf("invalid activation height %d for name %s", restriction.ActivationHeight, restriction.Name)
+	}
+	p.names[restriction.Name] = restriction
+	return nil
+}
+
+// 在height已经生效的限制，没有时返回nil
+func (p *NameRestrictions) Get(name string, height int) *NameRestriction {
+	restriction, ok := p.names[name]
+	if !ok || height < restriction.ActivationHeight {
+		return nil
+	}
+	return restriction
+}
+
+func (p *NameRestrictions) Len() int {
+	return len(p.names)
+}
@@ -0,0 +1,66 @@
+package common
+
+import "testing"
+
+func TestNameRestrictionActivation(t *testing.T) {
+	restrictions := NewNameRestrictions()
+	for _, r := range []*NameRestriction{
+		{Name: "brand.sats", Type: NAME_RESERVED, Reason: "trademark", ActivationHeight: 100},
+		{Name: "bad.sats", Type: NAME_BLOCKED, Reason: "illegal"},
+	} {
+		if err := restrictions.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		height int
+		typ    string
+		reason string
+	}{
+		{"brand.sats", 99, "", ""},
+		{"brand.sats", 100, NAME_RESERVED, "trademark"},
+		{"bad.sats", 0, NAME_BLOCKED, "illegal"},
+		{"other.sats", 100, "", ""},
+		// 名字需要是规范化以后的
+		{"BRAND.sats", 100, "", ""},
+	}
+	for _, c := range cases {
+		r := restrictions.Get(c.name, c.height)
+		if c.typ == "" {
+			if r != nil {
+				t.Errorf("%s at %d restricted %+v", c.name, c.height, r)
+			}
+			continue
+		}
+		if r == nil || r.Type != c.typ || r.Reason != c.reason {
+			t.Errorf("%s at %d: %+v, expected %s %s", c.name, c.height, r, c.typ, c.reason)
+		}
+	}
+
+	// 同名的限制被替换
+	restrictions.Add(&NameRestriction{Name: "brand.sats", Type: NAME_BLOCKED, ActivationHeight: 200})
+	if r := restrictions.Get("brand.sats", 150); r != nil {
+		t.Errorf("replaced restriction still active at 150")
+	}
+	if r := restrictions.Get("brand.sats", 200); r == nil || r.Type != NAME_BLOCKED {
+		t.Errorf("replaced restriction %+v", r)
+	}
+	if restrictions.Len() != 2 {
+		t.Errorf("%d restrictions, expected 2", restrictions.Len())
+	}
+}
+
+func TestNameRestrictionInvalid(t *testing.T) {
+	restrictions := NewNameRestrictions()
+	for _, r := range []*NameRestriction{
+		{Name: "", Type: NAME_RESERVED},
+		{Name: "a.sats", Type: NAME_TAKEN},
+		{Name: "a.sats", Type: NAME_BLOCKED, ActivationHeight: -1},
+	} {
+		if err := restrictions.Add(r); err == nil {
+			t.Errorf("invalid restriction %+v accepted", r)
+		}
+	}
+}
@@ -9,5 +9,10 @@ type NameInfo struct {
 	Base *InscribeBaseContent
 	Id   int64
 	Name string
-	KVs  map[string]*KeyValueInDB
+	// DNS中使用的ASCII形式，名字不能表示为IDNA时为空
+	Punycode string
+	Owner    string // 持有名字铭文的地址
+	// 当前生效的限制，比如注册以后才被屏蔽的名字
+	Restriction *NameRestriction
+	KVs         map[string]*KeyValueInDB
 }
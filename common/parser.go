@@ -1,17 +1,12 @@
 package common
 
 import (
-	"bytes"
-	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
-
-	"github.com/btcsuite/btcd/txscript"
-	"github.com/fxamacker/cbor/v2"
 )
 
 func GetRawData(txID string, network string) ([][]byte, error) {
@@ -62,251 +57,16 @@ func GetRawData(txID string, network string) ([][]byte, error) {
 	return rawData, nil
 }
 
-func readBytes(raw []byte, pointer *int, n int) []byte {
-	value := raw[*pointer : *pointer+n]
-	*pointer += n
-	return value
-}
-
-func getBeginPosition(raw []byte) int {
-	inscriptionMark := []byte{0, txscript.OP_IF, 3, 111, 114, 100}                         // "0063036f7264"
-	inscriptionMark2 := []byte{0, txscript.OP_IF, txscript.OP_PUSHDATA1, 3, 111, 114, 100} // "00634c036f7264"
-
-	position := bytes.Index(raw, inscriptionMark)
-	if position >= 0 {
-		return int(position + len(inscriptionMark))
-	}
-
-	position = bytes.Index(raw, inscriptionMark2)
-	if position >= 0 {
-		return int(position + len(inscriptionMark2))
-	}
-
-	return -1
-}
-
-// 从跳过信封头开始
-func getEndPosition(raw []byte) int {
-	length := len(raw)
-	i := 0
-	for i < length {
-		opcode := raw[i]
-		if opcode == txscript.OP_0 {
-			i++
-			getContentLength(raw, &i)
-		} else if txscript.OP_DATA_1 <= opcode && opcode <= txscript.OP_DATA_75 {
-			i++
-			i += int(opcode)
-		} else if opcode >= txscript.OP_PUSHDATA1 && opcode <= txscript.OP_PUSHDATA4 {
-			getPushDataLength(raw, &i)
-		} else if opcode >= txscript.OP_1 && opcode <= txscript.OP_16 {
-			i++
-		} else if opcode == txscript.OP_1NEGATE { // testnet: f8fc655ffe139d9952e673c53b7d15cb4b82de5ef036c7fc1211262bbd29bec8
-			i++
-		} else if opcode == txscript.OP_ENDIF {
-			return i
-		} else {
-			Log.Warnf("unsupport op_code %d ", opcode)
-			return -1
-		}
-	}
-
-	Log.Warnf("not find OP_ENDIF")
-	return -2
-}
-
-func readPushData(raw []byte, posPointer *int, opcode byte) []byte {
-	if txscript.OP_DATA_1 <= opcode && opcode <= txscript.OP_DATA_75 {
-		return readBytes(raw, posPointer, int(opcode))
-	}
-
-	if opcode >= txscript.OP_1 && opcode <= txscript.OP_16 {
-		byt := raw[*posPointer-1] - txscript.OP_1 + 1
-		return []byte{byt}
-	}
-
-	if opcode == txscript.OP_1NEGATE {
-		return []byte{opcode}
-	}
-
-	var numBytes int = 0
-	var size int = 0
-	switch opcode {
-	case txscript.OP_PUSHDATA1:
-		numBytes = 1
-	case txscript.OP_PUSHDATA2:
-		numBytes = 2
-	case txscript.OP_PUSHDATA4:
-		numBytes = 4
-	default:
-		return nil
-	}
-	sizeBytes := readBytes(raw, posPointer, numBytes)
-	switch opcode {
-	case txscript.OP_PUSHDATA1:
-		size = int(sizeBytes[0])
-	case txscript.OP_PUSHDATA2:
-		size = int(binary.LittleEndian.Uint16(sizeBytes))
-	case txscript.OP_PUSHDATA4:
-		size = int(binary.LittleEndian.Uint32(sizeBytes))
-	}
-	return readBytes(raw, posPointer, size)
-}
-
-func readContent(raw []byte, pos *int) (content []byte, err error) {
-	data := []byte{}
-	opcode := readBytes(raw, pos, 1)
-	if opcode[0] == txscript.OP_ENDIF {
-		*pos--
-		return nil, nil
-	}
-	chunk := readPushData(raw, pos, opcode[0])
-	for chunk != nil {
-		data = append(data, chunk...)
-		opcode = readBytes(raw, pos, 1)
-		if opcode[0] == txscript.OP_ENDIF {
-			*pos--
-			break
-		} else if opcode[0] == txscript.OP_0 {
-			// 某些情况会用OP_0分割，跳过
-			opcode = readBytes(raw, pos, 1)
-		}
-		chunk = readPushData(raw, pos, opcode[0])
-		if chunk == nil {
-			*pos--
-		}
-	}
-	return data, nil
-}
-
-func getPushDataLength(raw []byte, posPointer *int) int {
-	opcode := raw[*posPointer]
-	if txscript.OP_DATA_1 <= opcode && opcode <= txscript.OP_DATA_75 {
-		*posPointer++
-		*posPointer += int(opcode)
-		return int(opcode)
-	}
-
-	if opcode >= txscript.OP_1 && opcode <= txscript.OP_16 {
-		*posPointer++
-		return 1
-	}
-
-	var numBytes int = 0
-	var size int = 0
-	switch opcode {
-	case txscript.OP_PUSHDATA1:
-		numBytes = 1
-	case txscript.OP_PUSHDATA2:
-		numBytes = 2
-	case txscript.OP_PUSHDATA4:
-		numBytes = 4
-	default:
-		return 0
-	}
-	*posPointer++
-	sizeBytes := readBytes(raw, posPointer, numBytes)
-	switch opcode {
-	case txscript.OP_PUSHDATA1:
-		size = int(sizeBytes[0])
-	case txscript.OP_PUSHDATA2:
-		size = int(binary.LittleEndian.Uint16(sizeBytes))
-	case txscript.OP_PUSHDATA4:
-		size = int(binary.LittleEndian.Uint32(sizeBytes))
-	}
-	*posPointer += size
-	return size
-}
-
-func getContentLength(raw []byte, pos *int) int {
-	total := 0
-	length := getPushDataLength(raw, pos)
-	for length > 0 {
-		total += length
-		opcode := raw[*pos]
-		if opcode == txscript.OP_ENDIF {
-			break
-		} else if opcode == txscript.OP_0 {
-			// 某些情况会用OP_0分割，跳过
-			*pos++
-		}
-		length = getPushDataLength(raw, pos)
-	}
-	return total
-}
-
+// 按照ord的信封规则解析，每个信封是一次铭刻
 func ParseInscription(txWitness [][]byte) ([]map[int][]byte, error) {
-	// 规则：一个信封，就是一次铭刻。
-	// 无效情况：1. 存在不支持的指令；2.信封内部嵌套信封
-	// 可能存在任何一个witness
-
-	result := make([]map[int][]byte, 0)
-	for _, raw := range txWitness {
-
-		pos := int(0)
-		for pos < len(raw) {
-			begin := getBeginPosition(raw[pos:])
-			if begin < 0 {
-				break
-			}
-			begin += pos
-
-			end := getEndPosition(raw[begin:])
-			if end < 0 {
-				break
-			}
-			end += begin
-			pos = end + 1
-
-			envelope := raw[begin:pos]
-
-			fields := make(map[int][]byte)
-			length := end - begin
-			i := 0
-			for i < length {
-				opcode := envelope[i]
-				if opcode == txscript.OP_0 {
-					// body
-					i++
-					content, err := readContent(envelope, &i)
-					if err != nil {
-						break
-					}
-					fields[FIELD_CONTENT] = content
-				} else if opcode == txscript.OP_ENDIF {
-					i++
-					break
-				} else {
-					// read tags
-					i++
-					tagType := readPushData(envelope, &i, opcode)
-					if tagType == nil {
-						continue
-					} else {
-						opcode = envelope[i]
-						i++
-						tagContent := readPushData(envelope, &i, opcode)
-
-						if len(tagType) == 1 {
-							fields[int(tagType[0])] = tagContent
-						} else {
-							if tagContent == nil {
-								fields[FIELD_INVALID1] = tagType
-							} else if len(tagContent) == 1 {
-								fields[int(tagContent[0])] = tagType
-							} else {
-								fields[FIELD_INVALID1] = tagType
-								fields[FIELD_INVALID2] = tagContent
-							}
-						}
-					}
-				}
-			}
-			result = append(result, fields)
-		}
-
+	inscriptions, err := ParseEnvelopes(txWitness)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[int][]byte, 0, len(inscriptions))
+	for _, inscription := range inscriptions {
+		result = append(result, inscription.Fields())
 	}
-
 	return result, nil
 }
 
@@ -321,37 +81,14 @@ func ParseBrc20Content(content string) *Brc20BaseContent {
 	return &ret
 }
 
-func Cbor2json(cborData []byte) ([]byte, error) {
-	if cborData == nil {
-		return nil, fmt.Errorf("no data")
-	}
-	var decodedData map[string]string
-	err := cbor.Unmarshal(cborData, &decodedData)
-	if err != nil {
-		return nil, err
-	}
-	jsonData, err := json.Marshal(decodedData)
-	if err != nil {
-		return nil, err
-	}
-	return (jsonData), nil
-}
-
-func Json2cbor(jsonData []byte) ([]byte, error) {
-	if jsonData == nil {
-		return nil, fmt.Errorf("no data")
-	}
-	var decodedData map[string]string
-	err := json.Unmarshal(jsonData, &decodedData)
-	if err != nil {
-		return nil, err
-	}
-
-	cborData, err := cbor.Marshal(decodedData)
+// ticker中的空格也是有效字符，不做处理
+func ParseBrc20DeployContent(content string) *Brc20DeployContent {
+	var ret Brc20DeployContent
+	err := json.Unmarshal([]byte(content), &ret)
 	if err != nil {
-		return nil, err
+		return nil
 	}
-	return (cborData), nil
+	return &ret
 }
 
 func GetProtocol(fields map[int][]byte) (string, []byte) {
@@ -397,16 +134,34 @@ func PreprocessName(name string) string {
 }
 
 func IsValidSNSName(name string) bool {
-	if len(name) > MAX_NAME_LEN {
-		return false
-	}
+	return CheckSNSName(name) == ""
+}
+
+// 返回名字不符合规则的原因，符合规则时返回空，使用默认的长度规则
+func CheckSNSName(name string) string {
+	return CheckSNSNameAtHeight(name, 0, defaultNameLenPolicy)
+}
+
+// 长度规则和名字所在区块的高度有关
+func CheckSNSNameAtHeight(name string, height int, policy *NameLenPolicy) string {
 	parts := strings.Split(name, ".")
 	l := len(parts)
-	bReg := false
 	if l == 1 {
-		bReg = IsValidSat20Name(parts[0])
+		if parts[0] == "" {
+			return NAME_TOO_SHORT
+		}
+		if !IsValidName(parts[0]) {
+			return NAME_INVALID_CHARSET
+		}
 	} else if l == 2 {
-		bReg = IsValidName(parts[0]) && IsValidName(parts[1])
+		if parts[0] == "" || parts[1] == "" {
+			return NAME_INVALID_FORMAT
+		}
+		if !IsValidName(parts[0]) || !IsValidName(parts[1]) {
+			return NAME_INVALID_CHARSET
+		}
+	} else {
+		return NAME_INVALID_FORMAT
 	}
-	return bReg
+	return policy.Check(name, height)
 }
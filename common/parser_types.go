@@ -12,13 +12,25 @@ const (
 	FIELD_META_PROTOCOL    = 7
 	FIELD_CONTENT_ENCODING = 9
 	FIELD_DELEGATE         = 11
-	FIELD_INVALID1         = 20
-	FIELD_INVALID2         = 21
 )
 
 const MAX_NAME_LEN = 32
 const MIN_NAME_LEN = 3
 
+// 名字不能注册的原因
+const (
+	NAME_INVALID_CHARSET = "invalid_charset"
+	NAME_INVALID_FORMAT  = "invalid_format" // 最多一个'.'，且两边都不能为空
+	NAME_TOO_SHORT       = "too_short"
+	NAME_TOO_LONG        = "too_long"
+	NAME_RESERVED        = "reserved"
+	NAME_BLOCKED         = "blocked"
+	NAME_TAKEN           = "taken"
+	NAME_CONFUSABLE      = "confusable" // 和已注册的名字形似
+	NAME_PENDING         = "pending"    // 内存池中已有注册交易
+	NAME_NAMESPACE       = "namespace"  // 只能通过对应的协议注册
+)
+
 type OrdxBaseContent struct {
 	P  string `json:"p,omitempty"`
 	Op string `json:"op,omitempty"`
@@ -29,6 +41,15 @@ type Brc20BaseContent struct {
 	Ticker string `json:"tick,omitempty"`
 }
 
+// {"p":"brc-20","op":"deploy","tick":"ordi","max":"21000000","lim":"1000","dec":"18","self_mint":"true"}
+type Brc20DeployContent struct {
+	Brc20BaseContent
+	Max      string `json:"max"`
+	Lim      string `json:"lim,omitempty"`
+	Dec      string `json:"dec,omitempty"`
+	SelfMint string `json:"self_mint,omitempty"`
+}
+
 type PrimaryNameBaseContent struct {
 	OrdxBaseContent
 	Name   string `json:"name"`
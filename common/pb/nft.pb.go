@@ -36,10 +36,12 @@ type InscribeBaseContent struct {
 	ContentEncoding    []byte `protobuf:"bytes,9,opt,name=content_encoding,json=contentEncoding,proto3" json:"content_encoding,omitempty"`
 	Parent             string `protobuf:"bytes,10,opt,name=parent,proto3" json:"parent,omitempty"`
 	Delegate           string `protobuf:"bytes,11,opt,name=delegate,proto3" json:"delegate,omitempty"`
-	Id                 int64  `protobuf:"varint,12,opt,name=id,proto3" json:"id,omitempty"`
+	Id                 int64  `protobuf:"varint,12,opt,name=id,proto3" json:"id,omitempty"` // 近似ord的铭文编号，见indexer的numberInscription
 	Sat                int64  `protobuf:"varint,13,opt,name=sat,proto3" json:"sat,omitempty"`
 	TypeName           string `protobuf:"bytes,14,opt,name=typeName,proto3" json:"typeName,omitempty"`
 	UserData           []byte `protobuf:"bytes,15,opt,name=user_data,json=userData,proto3" json:"user_data,omitempty"`
+	Curse              string `protobuf:"bytes,16,opt,name=curse,proto3" json:"curse,omitempty"`
+	Vindicated         bool   `protobuf:"varint,17,opt,name=vindicated,proto3" json:"vindicated,omitempty"`
 }
 
 func (x *InscribeBaseContent) Reset() {
@@ -179,6 +181,20 @@ func (x *InscribeBaseContent) GetUserData() []byte {
 	return nil
 }
 
+func (x *InscribeBaseContent) GetCurse() string {
+	if x != nil {
+		return x.Curse
+	}
+	return ""
+}
+
+func (x *InscribeBaseContent) GetVindicated() bool {
+	if x != nil {
+		return x.Vindicated
+	}
+	return false
+}
+
 type NftsInSat struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -255,7 +271,7 @@ var File_common_pb_nft_proto protoreflect.FileDescriptor
 var file_common_pb_nft_proto_rawDesc = []byte{
 	0x0a, 0x13, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x70, 0x62, 0x2f, 0x6e, 0x66, 0x74, 0x2e,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x70, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e,
-	0x22, 0xe8, 0x03, 0x0a, 0x13, 0x49, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x42, 0x61, 0x73,
+	0x22, 0x9e, 0x04, 0x0a, 0x13, 0x49, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x42, 0x61, 0x73,
 	0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x69, 0x6e, 0x73, 0x63,
 	0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x0d, 0x69, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12,
@@ -285,18 +301,21 @@ var file_common_pb_nft_proto_rawDesc = []byte{
 	0x61, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x79, 0x70, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x0e,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x79, 0x70, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1b,
 	0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x18, 0x0f, 0x20, 0x01, 0x28,
-	0x0c, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x44, 0x61, 0x74, 0x61, 0x22, 0x94, 0x01, 0x0a, 0x09,
-	0x4e, 0x66, 0x74, 0x73, 0x49, 0x6e, 0x53, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x61, 0x74,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x61, 0x74, 0x12, 0x28, 0x0a, 0x10, 0x6f,
-	0x77, 0x6e, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x5f, 0x69, 0x64, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x41, 0x64, 0x64, 0x72,
-	0x65, 0x73, 0x73, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x74, 0x78, 0x6f, 0x5f, 0x69, 0x64,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x75, 0x74, 0x78, 0x6f, 0x49, 0x64, 0x12, 0x32,
-	0x0a, 0x04, 0x6e, 0x66, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70,
-	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x49, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x62,
-	0x65, 0x42, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x6e, 0x66,
-	0x74, 0x73, 0x42, 0x0c, 0x5a, 0x0a, 0x2f, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x70, 0x62,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x0c, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x44, 0x61, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x63,
+	0x75, 0x72, 0x73, 0x65, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x75, 0x72, 0x73,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x76, 0x69, 0x6e, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18,
+	0x11, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x76, 0x69, 0x6e, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x64, 0x22, 0x94, 0x01, 0x0a, 0x09, 0x4e, 0x66, 0x74, 0x73, 0x49, 0x6e, 0x53, 0x61, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x73, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x61,
+	0x74, 0x12, 0x28, 0x0a, 0x10, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x6f, 0x77, 0x6e,
+	0x65, 0x72, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x75,
+	0x74, 0x78, 0x6f, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x75, 0x74,
+	0x78, 0x6f, 0x49, 0x64, 0x12, 0x32, 0x0a, 0x04, 0x6e, 0x66, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x49,
+	0x6e, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x42, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65,
+	0x6e, 0x74, 0x52, 0x04, 0x6e, 0x66, 0x74, 0x73, 0x42, 0x0c, 0x5a, 0x0a, 0x2f, 0x63, 0x6f, 0x6d,
+	0x6d, 0x6f, 0x6e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -17,6 +17,8 @@ type Input struct {
 	Vout    int64         `json:"vout"`
 
 	Witness wire.TxWitness `json:"witness"`
+	// 拉取区块时预先解析好的铭文信封
+	Inscriptions []*Inscription `json:"-"`
 }
 
 type ScriptPubKey struct {
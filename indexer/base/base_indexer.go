@@ -38,30 +38,40 @@ type BaseIndexer struct {
 	stats      *SyncStats
 	lastHeight int // 内存数据同步区块
 	lastHash   string
-	blocksChan chan *common.Block
 
 	// 配置参数
 	periodFlushToDB  int
 	keepBlockHistory int
-	chaincfgParam    *chaincfg.Params
+	fetchWorkers     int
+	blockPrefetch    int
+	// 等待交付的区块估算的字节数超过时暂停预取
+	blockPrefetchBytes int
+	chaincfgParam      *chaincfg.Params
+	blockSource        BlockSource
+	startHeight        int // 数据库为空时从这个高度开始同步
 
 	blockprocCB BlockProcCallback
 	updateDBCB  UpdateDBCallback
 }
 
 const BLOCK_PREFETCH = 12
+const BLOCK_PREFETCH_BYTES = 256 << 20
+const FETCH_WORKERS = 4
 
 func NewBaseIndexer(
 	basicDB *badger.DB,
 	chaincfgParam *chaincfg.Params,
 ) *BaseIndexer {
 	indexer := &BaseIndexer{
-		db:               basicDB,
-		stats:            &SyncStats{},
-		periodFlushToDB:  500,
-		keepBlockHistory: 6,
-		blocksChan:       make(chan *common.Block, BLOCK_PREFETCH),
-		chaincfgParam:    chaincfgParam,
+		db:                 basicDB,
+		stats:              &SyncStats{},
+		periodFlushToDB:    500,
+		keepBlockHistory:   6,
+		fetchWorkers:       FETCH_WORKERS,
+		blockPrefetch:      BLOCK_PREFETCH,
+		blockPrefetchBytes: BLOCK_PREFETCH_BYTES,
+		chaincfgParam:      chaincfgParam,
+		blockSource:        &RpcBlockSource{},
 	}
 	return indexer
 }
@@ -75,7 +85,6 @@ func (b *BaseIndexer) Init(cb1 BlockProcCallback, cb2 UpdateDBCallback) {
 
 func (b *BaseIndexer) reset() {
 	b.loadSyncStatsFromDB()
-	b.blocksChan = make(chan *common.Block, BLOCK_PREFETCH)
 }
 
 // 只保存UpdateDB需要用的数据
@@ -83,6 +92,12 @@ func (b *BaseIndexer) Clone() *BaseIndexer {
 	startTime := time.Now()
 	newInst := NewBaseIndexer(b.db, b.chaincfgParam)
 
+	newInst.periodFlushToDB = b.periodFlushToDB
+	newInst.fetchWorkers = b.fetchWorkers
+	newInst.blockPrefetch = b.blockPrefetch
+	newInst.blockPrefetchBytes = b.blockPrefetchBytes
+	newInst.blockSource = b.blockSource
+	newInst.startHeight = b.startHeight
 	newInst.lastHash = b.lastHash
 	newInst.lastHeight = b.lastHeight
 	newInst.stats = b.stats
@@ -98,6 +113,30 @@ func (b *BaseIndexer) WithPeriodFlushToDB(value int) *BaseIndexer {
 	return b
 }
 
+// 并发拉取和解析区块的线程数
+func (b *BaseIndexer) WithFetchWorkers(value int) *BaseIndexer {
+	b.fetchWorkers = value
+	return b
+}
+
+// 内存中最多预取的区块数，包括正在拉取的
+func (b *BaseIndexer) WithBlockPrefetch(value int) *BaseIndexer {
+	b.blockPrefetch = value
+	return b
+}
+
+// 默认从bitcoind获取区块
+func (b *BaseIndexer) WithBlockSource(source BlockSource) *BaseIndexer {
+	b.blockSource = source
+	return b
+}
+
+// 默认从创世区块开始，回放从中间高度录制的区块时需要设置，只对空数据库有效，要在Init之前调用
+func (b *BaseIndexer) WithStartHeight(height int) *BaseIndexer {
+	b.startHeight = height
+	return b
+}
+
 // only call in compiling data
 func (b *BaseIndexer) forceUpdateDB() {
 	startTime := time.Now()
@@ -149,7 +188,8 @@ func (b *BaseIndexer) syncToBlock(height int, stopChan chan struct{}) int {
 	logProgressPeriod := 1
 
 	stopBlockFetcherChan := make(chan struct{})
-	go b.spawnBlockFetcher(start, height, stopBlockFetcherChan)
+	defer close(stopBlockFetcherChan)
+	blocksChan := b.spawnBlockFetcher(start, height, stopBlockFetcherChan)
 
 	for i := start; i <= height; i++ {
 		select {
@@ -157,7 +197,7 @@ func (b *BaseIndexer) syncToBlock(height int, stopChan chan struct{}) int {
 			b.forceMajeure()
 			return -1
 		default:
-			block := <-b.blocksChan
+			block := <-blocksChan
 
 			if block == nil {
 				common.Log.Panicf("BaseIndexer.SyncToBlock-> fetch block failed %d", i)
@@ -169,10 +209,9 @@ func (b *BaseIndexer) syncToBlock(height int, stopChan chan struct{}) int {
 				common.Log.Panicf("BaseIndexer.SyncToBlock-> expected block height %d, got %d", i, block.Height)
 			}
 
-			// detect reorgs
-			if i > 0 && block.PrevBlockHash != b.lastHash {
+			// detect reorgs, there is nothing to compare for the first block
+			if i > 0 && b.lastHash != "" && block.PrevBlockHash != b.lastHash {
 				common.Log.WithField("BaseIndexer.SyncToBlock-> height", i).Warn("reorg detected")
-				stopBlockFetcherChan <- struct{}{}
 				return block.Height
 			}
 
@@ -207,7 +246,7 @@ func (b *BaseIndexer) syncToBlock(height int, stopChan chan struct{}) int {
 }
 
 func (b *BaseIndexer) SyncToChainTip(stopChan chan struct{}) int {
-	count, err := getBlockCount()
+	count, err := b.blockSource.GetBlockCount()
 	if err != nil {
 		common.Log.Errorf("failed to get block count %v", err)
 		return -1
@@ -230,7 +269,7 @@ func (b *BaseIndexer) loadSyncStatsFromDB() {
 		err := common.GetValueFromDB([]byte(SyncStatsKey), txn, syncStats)
 		if err == badger.ErrKeyNotFound {
 			common.Log.Info("BaseIndexer.LoadSyncStatsFromDB-> No sync stats found in db")
-			syncStats.SyncHeight = -1
+			syncStats.SyncHeight = b.startHeight - 1
 		} else if err != nil {
 			return err
 		}
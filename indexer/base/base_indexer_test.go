@@ -0,0 +1,66 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dgraph-io/badger/v4"
+)
+
+func newFixtureIndexer(t *testing.T, source BlockSource, startHeight int) (*BaseIndexer, *[]int) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLoggingLevel(badger.WARNING))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	heights := make([]int, 0)
+	indexer := NewBaseIndexer(db, &chaincfg.RegressionNetParams).
+		WithBlockSource(source).
+		WithStartHeight(startHeight)
+	indexer.Init(func(block *common.Block) {
+		heights = append(heights, block.Height)
+	}, func() {})
+	return indexer, &heights
+}
+
+func TestSyncDetectReorg(t *testing.T) {
+	source, err := LoadFixture(reorgFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexer, heights := newFixtureIndexer(t, source, 0)
+
+	if ret := indexer.SyncToChainTip(make(chan struct{})); ret != 0 {
+		t.Fatalf("sync returned %d, expected 0", ret)
+	}
+	if indexer.GetHeight() != 3 || len(*heights) != 4 {
+		t.Fatalf("synced to %d with blocks %v", indexer.GetHeight(), *heights)
+	}
+
+	source.SwitchFork("reorg")
+	// 分叉的第4个区块连不上已同步的第3个区块
+	if ret := indexer.SyncToChainTip(make(chan struct{})); ret != 4 {
+		t.Fatalf("sync returned %d, expected reorg at 4", ret)
+	}
+	if indexer.GetHeight() != 3 {
+		t.Fatalf("height %d after reorg detected, expected 3", indexer.GetHeight())
+	}
+}
+
+func TestSyncFromStartHeight(t *testing.T) {
+	source, err := LoadFixture(reorgFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 空数据库从中间高度开始，第一个区块没有可以比较的前一个区块，不是reorg
+	indexer, heights := newFixtureIndexer(t, source, 2)
+
+	if ret := indexer.SyncToChainTip(make(chan struct{})); ret != 0 {
+		t.Fatalf("sync returned %d, expected 0", ret)
+	}
+	if len(*heights) != 2 || (*heights)[0] != 2 || (*heights)[1] != 3 {
+		t.Fatalf("processed blocks %v, expected [2 3]", *heights)
+	}
+}
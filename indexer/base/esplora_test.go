@@ -0,0 +1,126 @@
+package base
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OLProtocol/ordx/share/esplora"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// coinbase和一个有铭文的交易，输出是taproot地址和OP_RETURN
+func esploraTestBlock(t *testing.T) *wire.MsgBlock {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff}, []byte{5, 0}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(50, []byte{txscript.OP_TRUE}))
+
+	script, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_FALSE).AddOp(txscript.OP_IF).AddData([]byte("ord")).
+		AddOp(txscript.OP_DATA_1).AddOp(1).AddData([]byte("text/plain")).
+		AddOp(txscript.OP_0).AddData([]byte("alpha.sats")).
+		AddOp(txscript.OP_ENDIF).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(2)
+	input := wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{7}, Index: 3}, nil, nil)
+	input.Witness = wire.TxWitness{make([]byte, 64), script, append([]byte{0xc1}, make([]byte, 32)...)}
+	tx.AddTxIn(input)
+	tx.AddTxOut(wire.NewTxOut(546, append([]byte{txscript.OP_1, txscript.OP_DATA_32}, bytes.Repeat([]byte{1}, 32)...)))
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN, txscript.OP_DATA_1, 1}))
+
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &chainhash.Hash{4}, &chainhash.Hash{}, 0, 0))
+	block.Header.Timestamp = time.Unix(1700000000, 0)
+	block.AddTransaction(coinbase)
+	block.AddTransaction(tx)
+	return block
+}
+
+func newEsploraServer(t *testing.T, block *wire.MsgBlock) *esplora.Client {
+	var buf bytes.Buffer
+	if err := block.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	hash := block.BlockHash().String()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/block-height/5", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(hash))
+	})
+	mux.HandleFunc("/block-height/6", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted"))
+	})
+	mux.HandleFunc("/block/"+hash+"/raw", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/block/corrupted/raw", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes()[:100])
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := esplora.NewClient(server.URL, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// esplora返回的区块和bitcoind一样反序列化。区块中没有prevout，输入只有花费的 txid:vout
+func TestFetchBlockFromEsplora(t *testing.T) {
+	msgBlock := esploraTestBlock(t)
+	b := newTestFetcher(newEsploraServer(t, msgBlock), 1, 1, BLOCK_PREFETCH_BYTES)
+
+	block := b.fetchBlock(5)
+	if block == nil {
+		t.Fatalf("block not fetched")
+	}
+	if block.Height != 5 || block.Hash != msgBlock.BlockHash().String() ||
+		block.PrevBlockHash != (chainhash.Hash{4}).String() || block.Timestamp.Unix() != 1700000000 {
+		t.Fatalf("block %d %s prev %s time %v", block.Height, block.Hash, block.PrevBlockHash, block.Timestamp)
+	}
+	if len(block.Transactions) != 2 {
+		t.Fatalf("%d transactions", len(block.Transactions))
+	}
+
+	tx := block.Transactions[1]
+	if tx.Txid != msgBlock.Transactions[1].TxHash().String() {
+		t.Fatalf("txid %s", tx.Txid)
+	}
+	input := tx.Inputs[0]
+	if input.Txid != (chainhash.Hash{7}).String() || input.Vout != 3 {
+		t.Fatalf("input spends %s:%d", input.Txid, input.Vout)
+	}
+	if len(input.Inscriptions) != 1 || string(input.Inscriptions[0].Body) != "alpha.sats" {
+		t.Fatalf("input inscriptions %v", input.Inscriptions)
+	}
+
+	if len(tx.Outputs) != 2 {
+		t.Fatalf("%d outputs", len(tx.Outputs))
+	}
+	output := tx.Outputs[0]
+	if output.Value != 546 || output.N != 0 || output.Height != 5 || output.TxId != 1 ||
+		!strings.HasPrefix(output.Address.Addresses[0], "bc1p") {
+		t.Fatalf("output %+v address %v", output, output.Address.Addresses)
+	}
+	if tx.Outputs[1].N != 1 || tx.Outputs[1].Address.Addresses[0] != "OP_RETURN" {
+		t.Fatalf("op_return output %+v", tx.Outputs[1].Address)
+	}
+}
+
+func TestFetchBlockFromEsploraErrors(t *testing.T) {
+	b := newTestFetcher(newEsploraServer(t, esploraTestBlock(t)), 1, 1, BLOCK_PREFETCH_BYTES)
+	// 没有这个高度
+	if block := b.fetchBlock(7); block != nil {
+		t.Fatalf("block fetched for unknown height")
+	}
+	// 区块数据不完整
+	if block := b.fetchBlock(6); block != nil {
+		t.Fatalf("corrupted block decoded")
+	}
+}
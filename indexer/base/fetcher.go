@@ -1,7 +1,7 @@
 package base
 
 import (
-	"encoding/hex"
+	"sync"
 
 	"github.com/OLProtocol/ordx/common"
 	"github.com/btcsuite/btcd/btcutil"
@@ -9,26 +9,25 @@ import (
 )
 
 func (b *BaseIndexer) fetchBlock(height int) *common.Block {
-	hash, err := getBlockHash(uint64(height))
+	hash, err := b.blockSource.GetBlockHash(uint64(height))
 	if err != nil {
 		common.Log.Errorf("getBlockHash %d failed. %v", height, err)
 		return nil
 		//common.Log.Fatalln(err)
 	}
 
-	rawBlock, err := getRawBlock(hash)
+	blockData, err := b.blockSource.GetRawBlock(hash)
 	if err != nil {
 		common.Log.Errorf("getRawBlock %d %s failed. %v", height, hash, err)
 		return nil
 		//common.Log.Fatalln(err)
 	}
-	blockData, err := hex.DecodeString(rawBlock)
-	if err != nil {
-		common.Log.Errorf("DecodeString %d %s failed. %v", height, rawBlock, err)
-		return nil
-		//common.Log.Panicf("BaseIndexer.fetchBlock-> Failed to decode block: %v", err)
-	}
 
+	return b.decodeBlock(height, blockData)
+}
+
+// 反序列化区块，同时解析每个输入中的铭文信封，这部分在fetch的工作线程中完成
+func (b *BaseIndexer) decodeBlock(height int, blockData []byte) *common.Block {
 	// Deserialize the bytes into a btcutil.Block.
 	block, err := btcutil.NewBlockFromBytes(blockData)
 	if err != nil {
@@ -43,10 +42,18 @@ func (b *BaseIndexer) fetchBlock(height int) *common.Block {
 		inputs := []*common.Input{}
 		outputs := []*common.Output{}
 
-		for _, v := range tx.MsgTx().TxIn {
+		for j, v := range tx.MsgTx().TxIn {
 			txid := v.PreviousOutPoint.Hash.String()
 			vout := v.PreviousOutPoint.Index
 			input := &common.Input{Txid: txid, Vout: int64(vout), Witness: v.Witness}
+			if len(v.Witness) > 0 {
+				var err error
+				input.Inscriptions, err = common.ParseEnvelopes(v.Witness)
+				if err != nil {
+					// 和ord一样，这个输入中的信封都无效
+					common.Log.Warnf("%d %s input %d: %v", height, tx.Hash().String(), j, err)
+				}
+			}
 			inputs = append(inputs, input)
 		}
 
@@ -106,19 +113,164 @@ func (b *BaseIndexer) fetchBlock(height int) *common.Block {
 	return bl
 }
 
-// Prefetches blocks from bitcoind and sends them to the blocksChan
-func (b *BaseIndexer) spawnBlockFetcher(startHeigh int, endHeight int, stopChan chan struct{}) {
-	currentHeight := startHeigh
-	for currentHeight <= endHeight {
-		select {
-		case <-stopChan:
-			return
-		default:
-			block := b.fetchBlock(currentHeight)
-			b.blocksChan <- block
-			currentHeight += 1
+type fetchResult struct {
+	height int
+	block  *common.Block
+	size   int
+}
+
+// 估算解析后的区块占用的内存，主要是witness和解码后的铭文内容
+func blockMemSize(block *common.Block) int {
+	if block == nil {
+		return 0
+	}
+	size := 0
+	for _, tx := range block.Transactions {
+		size += 128
+		for _, input := range tx.Inputs {
+			size += 128
+			for _, item := range input.Witness {
+				size += len(item)
+			}
+			for _, insc := range input.Inscriptions {
+				size += len(insc.Body) + len(insc.Content) + len(insc.Metadata)
+			}
+		}
+		size += 128 * len(tx.Outputs)
+	}
+	return size
+}
+
+// 预取的区块按数量和估算的字节数限制
+type prefetchBudget struct {
+	mutex     sync.Mutex
+	cond      *sync.Cond
+	blocks    int // 在途和等待排序的区块
+	bytes     int // 已经解析还没有交付的区块
+	maxBlocks int
+	maxBytes  int
+	stopped   bool
+}
+
+func newPrefetchBudget(maxBlocks, maxBytes int) *prefetchBudget {
+	p := &prefetchBudget{maxBlocks: maxBlocks, maxBytes: maxBytes}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+// 等待可以再拉取一个区块，停止后返回false。没有等待交付的区块时不检查字节数，
+// 单个超过限制的区块也能拉取
+func (p *prefetchBudget) acquire() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for !p.stopped && (p.blocks >= p.maxBlocks || (p.bytes > 0 && p.bytes >= p.maxBytes)) {
+		p.cond.Wait()
+	}
+	if p.stopped {
+		return false
+	}
+	p.blocks++
+	return true
+}
+
+func (p *prefetchBudget) fetched(size int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes += size
+}
+
+// 区块已经交付
+func (p *prefetchBudget) release(size int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.blocks--
+	p.bytes -= size
+	p.cond.Broadcast()
+}
+
+func (p *prefetchBudget) stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.stopped = true
+	p.cond.Broadcast()
+}
+
+// 多个工作线程预取区块，按高度顺序交付，数量和字节数受prefetchBudget限制，关闭stopChan时停止
+func (b *BaseIndexer) spawnBlockFetcher(startHeigh int, endHeight int, stopChan chan struct{}) <-chan *common.Block {
+	workers := b.fetchWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	prefetch := b.blockPrefetch
+	if prefetch < workers {
+		prefetch = workers
+	}
+
+	blocksChan := make(chan *common.Block, 1)
+	heightChan := make(chan int)
+	resultChan := make(chan *fetchResult, workers)
+	budget := newPrefetchBudget(prefetch, b.blockPrefetchBytes)
+	go func() {
+		<-stopChan
+		budget.stop()
+	}()
+
+	go func() {
+		defer close(heightChan)
+		for h := startHeigh; h <= endHeight; h++ {
+			if !budget.acquire() {
+				return
+			}
+			select {
+			case <-stopChan:
+				return
+			case heightChan <- h:
+			}
 		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for h := range heightChan {
+				block := b.fetchBlock(h)
+				result := &fetchResult{height: h, block: block, size: blockMemSize(block)}
+				budget.fetched(result.size)
+				select {
+				case <-stopChan:
+					return
+				case resultChan <- result:
+				}
+			}
+		}()
 	}
 
-	<-stopChan
+	go func() {
+		pending := make(map[int]*fetchResult)
+		next := startHeigh
+		for next <= endHeight {
+			select {
+			case <-stopChan:
+				return
+			case result := <-resultChan:
+				pending[result.height] = result
+			}
+
+			for {
+				result, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				select {
+				case <-stopChan:
+					return
+				case blocksChan <- result.block:
+				}
+				budget.release(result.size)
+				next++
+			}
+		}
+	}()
+
+	return blocksChan
 }
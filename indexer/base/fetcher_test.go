@@ -0,0 +1,146 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// 高度越低返回越慢，工作线程按相反的顺序完成
+type slowBlockSource struct {
+	endHeight int
+	delay     time.Duration
+
+	mutex     sync.Mutex
+	fetched   int
+	consumed  int
+	maxQueued int
+}
+
+func (s *slowBlockSource) GetBlockCount() (uint64, error) {
+	return uint64(s.endHeight), nil
+}
+
+func (s *slowBlockSource) GetBlockHash(height uint64) (string, error) {
+	return fmt.Sprintf("h%d", height), nil
+}
+
+func (s *slowBlockSource) GetRawBlock(blockHash string) ([]byte, error) {
+	height, err := strconv.Atoi(strings.TrimPrefix(blockHash, "h"))
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(time.Duration(s.endHeight-height) * s.delay)
+
+	s.mutex.Lock()
+	s.fetched++
+	if s.fetched-s.consumed > s.maxQueued {
+		s.maxQueued = s.fetched - s.consumed
+	}
+	s.mutex.Unlock()
+	return testBlock(height)
+}
+
+func (s *slowBlockSource) consume() {
+	s.mutex.Lock()
+	s.consumed++
+	s.mutex.Unlock()
+}
+
+// 只有coinbase的区块，用时间戳区分高度
+func testBlock(height int) ([]byte, error) {
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0xffffffff}, []byte{byte(height), 0}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(50, []byte{0x51}))
+	block := wire.NewMsgBlock(&wire.BlockHeader{Timestamp: time.Unix(int64(height), 0)})
+	block.AddTransaction(coinbase)
+	var buf bytes.Buffer
+	err := block.Serialize(&buf)
+	return buf.Bytes(), err
+}
+
+func newTestFetcher(source BlockSource, workers, prefetch, prefetchBytes int) *BaseIndexer {
+	b := NewBaseIndexer(nil, &chaincfg.MainNetParams)
+	b.WithBlockSource(source).WithFetchWorkers(workers).WithBlockPrefetch(prefetch)
+	b.blockPrefetchBytes = prefetchBytes
+	return b
+}
+
+func TestBlockFetcherOrder(t *testing.T) {
+	source := &slowBlockSource{endHeight: 20, delay: 2 * time.Millisecond}
+	b := newTestFetcher(source, 4, 8, BLOCK_PREFETCH_BYTES)
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	blocksChan := b.spawnBlockFetcher(1, source.endHeight, stopChan)
+	for h := 1; h <= source.endHeight; h++ {
+		select {
+		case block := <-blocksChan:
+			if block == nil || block.Height != h || block.Timestamp.Unix() != int64(h) {
+				t.Fatalf("expected block %d, got %+v", h, block)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block %d not delivered", h)
+		}
+	}
+}
+
+func TestBlockFetcherPrefetchBytes(t *testing.T) {
+	for _, tc := range []struct {
+		prefetchBytes int
+		maxQueued     int
+	}{
+		// 等待交付的区块超过字节数限制后只剩在途的区块，
+		// 另外还有通道中的一个和接收方正在处理的一个
+		{prefetchBytes: 1, maxQueued: 4 + 2},
+		{prefetchBytes: BLOCK_PREFETCH_BYTES, maxQueued: 12 + 2},
+	} {
+		source := &slowBlockSource{endHeight: 40}
+		b := newTestFetcher(source, 4, 12, tc.prefetchBytes)
+
+		stopChan := make(chan struct{})
+		blocksChan := b.spawnBlockFetcher(1, source.endHeight, stopChan)
+		for h := 1; h <= source.endHeight; h++ {
+			time.Sleep(2 * time.Millisecond)
+			block := <-blocksChan
+			if block == nil || block.Height != h {
+				t.Fatalf("expected block %d, got %+v", h, block)
+			}
+			source.consume()
+		}
+		close(stopChan)
+
+		if source.maxQueued > tc.maxQueued {
+			t.Fatalf("prefetchBytes %d: %d blocks queued, limit %d",
+				tc.prefetchBytes, source.maxQueued, tc.maxQueued)
+		}
+	}
+}
+
+func TestBlockFetcherStop(t *testing.T) {
+	source := &slowBlockSource{endHeight: 100}
+	b := newTestFetcher(source, 2, 4, 1)
+
+	stopChan := make(chan struct{})
+	blocksChan := b.spawnBlockFetcher(1, source.endHeight, stopChan)
+	<-blocksChan
+	close(stopChan)
+	// 停止后不再拉取
+	time.Sleep(50 * time.Millisecond)
+	source.mutex.Lock()
+	fetched := source.fetched
+	source.mutex.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if source.fetched != fetched {
+		t.Fatalf("fetching after stop: %d -> %d", fetched, source.fetched)
+	}
+}
@@ -0,0 +1,392 @@
+package base
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const FIXTURE_MANIFEST = "manifest.json"
+
+// manifest.json 描述一条链，以及可以切换过去的分叉
+type FixtureManifest struct {
+	Blocks []*FixtureBlockFile `json:"blocks"`
+	Forks  []*FixtureFork      `json:"forks,omitempty"`
+}
+
+type FixtureFork struct {
+	Name   string              `json:"name"`
+	Blocks []*FixtureBlockFile `json:"blocks"`
+}
+
+// file 是相对manifest的路径，.hex为十六进制文本，其他为二进制
+type FixtureBlockFile struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash,omitempty"`
+	File   string `json:"file"`
+}
+
+type fixtureBlock struct {
+	hash string
+	raw  []byte
+}
+
+// 从本地文件回放区块，用于不依赖节点的测试
+type FixtureBlockSource struct {
+	mutex sync.RWMutex
+	start int             // 第一个区块的高度，录制的区块不一定从0开始
+	chain []*fixtureBlock // chain[i] 的高度是 start+i
+	forks map[string]map[int]*fixtureBlock
+	stale []*fixtureBlock // 切换分叉时被替换的区块，和节点一样仍然可以按hash读取
+	tip   int             // 对外可见的最高区块，-1表示全部可见
+}
+
+func NewFixtureBlockSource() *FixtureBlockSource {
+	return &FixtureBlockSource{
+		chain: make([]*fixtureBlock, 0),
+		forks: make(map[string]map[int]*fixtureBlock),
+		tip:   -1,
+	}
+}
+
+// path 可以是manifest文件，或者包含manifest.json的目录，
+// 或者只包含 <height>.blk / <height>.hex 区块文件的目录
+func LoadFixture(path string) (*FixtureBlockSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := path
+	if info.IsDir() {
+		manifestPath = filepath.Join(path, FIXTURE_MANIFEST)
+		if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+			return loadFixtureDir(path)
+		}
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var manifest FixtureManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture manifest %s, %v", manifestPath, err)
+	}
+
+	dir := filepath.Dir(manifestPath)
+	source := NewFixtureBlockSource()
+	for _, file := range manifest.Blocks {
+		block, err := loadFixtureBlock(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		err = source.setBlock(file.Height, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, fork := range manifest.Forks {
+		blocks := make(map[int]*fixtureBlock)
+		for _, file := range fork.Blocks {
+			block, err := loadFixtureBlock(dir, file)
+			if err != nil {
+				return nil, err
+			}
+			blocks[file.Height] = block
+		}
+		source.forks[fork.Name] = blocks
+	}
+
+	return source, nil
+}
+
+func loadFixtureDir(dir string) (*FixtureBlockSource, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*FixtureBlockFile, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if ext != ".blk" && ext != ".hex" {
+			continue
+		}
+		// <height>.blk 或者 <height>-<anything>.blk
+		prefix, _, _ := strings.Cut(strings.TrimSuffix(name, ext), "-")
+		height, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture block file name %s", name)
+		}
+		files = append(files, &FixtureBlockFile{Height: height, File: name})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Height < files[j].Height
+	})
+
+	source := NewFixtureBlockSource()
+	for _, file := range files {
+		block, err := loadFixtureBlock(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		err = source.setBlock(file.Height, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return source, nil
+}
+
+func loadFixtureBlock(dir string, file *FixtureBlockFile) (*fixtureBlock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, file.File))
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(file.File) == ".hex" {
+		data, err = hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex in %s, %v", file.File, err)
+		}
+	}
+
+	block, err := newFixtureBlock(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block in %s, %v", file.File, err)
+	}
+	if file.Hash != "" && file.Hash != block.hash {
+		return nil, fmt.Errorf("block %s has hash %s, expected %s", file.File, block.hash, file.Hash)
+	}
+	return block, nil
+}
+
+func newFixtureBlock(raw []byte) (*fixtureBlock, error) {
+	var msgBlock wire.MsgBlock
+	err := msgBlock.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &fixtureBlock{hash: msgBlock.BlockHash().String(), raw: raw}, nil
+}
+
+func (s *FixtureBlockSource) setBlock(height int, block *fixtureBlock) error {
+	if len(s.chain) == 0 {
+		s.start = height
+	} else if height != s.start+len(s.chain) {
+		return fmt.Errorf("fixture block at height %d is not contiguous, expected %d", height, s.start+len(s.chain))
+	}
+	s.chain = append(s.chain, block)
+	return nil
+}
+
+// 第一个区块的高度，indexer需要从这里开始同步
+func (s *FixtureBlockSource) StartHeight() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.start
+}
+
+// 追加一个区块到链上，返回它的高度
+func (s *FixtureBlockSource) AddBlock(raw []byte) (int, error) {
+	block, err := newFixtureBlock(raw)
+	if err != nil {
+		return -1, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	height := s.start + len(s.chain)
+	return height, s.setBlock(height, block)
+}
+
+// 从height开始的区块被替换成分叉中的区块
+func (s *FixtureBlockSource) SwitchFork(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	blocks, ok := s.forks[name]
+	if !ok {
+		return fmt.Errorf("fork %s not exist", name)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("fork %s has no blocks", name)
+	}
+	heights := make([]int, 0, len(blocks))
+	for height := range blocks {
+		heights = append(heights, height)
+	}
+	sort.Ints(heights)
+	start := heights[0]
+	for i, height := range heights {
+		if height != start+i {
+			return fmt.Errorf("fork %s is not contiguous, missing block at height %d", name, start+i)
+		}
+	}
+	if start < s.start || start > s.start+len(s.chain) {
+		return fmt.Errorf("fork %s at height %d can't connect to chain %d-%d", name, start, s.start, s.start+len(s.chain)-1)
+	}
+
+	s.stale = append(s.stale, s.chain[start-s.start:]...)
+	s.chain = s.chain[:start-s.start]
+	for _, height := range heights {
+		s.chain = append(s.chain, blocks[height])
+	}
+	return nil
+}
+
+// 限制对外可见的链高度，用于一步步回放，-1表示全部可见
+func (s *FixtureBlockSource) SetTip(height int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tip = height
+}
+
+func (s *FixtureBlockSource) visibleHeight() int {
+	height := s.start + len(s.chain) - 1
+	if s.tip >= 0 && s.tip < height {
+		height = s.tip
+	}
+	return height
+}
+
+func (s *FixtureBlockSource) GetBlockCount() (uint64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	height := s.visibleHeight()
+	if len(s.chain) == 0 || height < s.start {
+		return 0, fmt.Errorf("fixture chain is empty")
+	}
+	return uint64(height), nil
+}
+
+func (s *FixtureBlockSource) GetBlockHash(height uint64) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if int(height) < s.start || int(height) > s.visibleHeight() {
+		return "", fmt.Errorf("block height %d out of range", height)
+	}
+	return s.chain[int(height)-s.start].hash, nil
+}
+
+func (s *FixtureBlockSource) GetRawBlock(blockHash string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, block := range s.chain {
+		if block.hash == blockHash {
+			return block.raw, nil
+		}
+	}
+	for _, blocks := range s.forks {
+		for _, block := range blocks {
+			if block.hash == blockHash {
+				return block.raw, nil
+			}
+		}
+	}
+	for _, block := range s.stale {
+		if block.hash == blockHash {
+			return block.raw, nil
+		}
+	}
+	return nil, fmt.Errorf("block %s not found", blockHash)
+}
+
+// 从在线的数据源抓取[start, end]区块，保存为LoadFixture可以读取的格式
+func RecordFixture(source BlockSource, dir string, start, end int) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	blocks, err := recordBlocks(source, dir, start, end, "")
+	if err != nil {
+		return err
+	}
+	return saveFixtureManifest(dir, &FixtureManifest{Blocks: blocks})
+}
+
+// 把数据源当前的[start, end]区块作为分叉加入已经录制的目录，同名的分叉会被替换。
+// 节点发生reorg以后，或者使用在另一条链上的节点录制
+func RecordFixtureFork(source BlockSource, dir, name string, start, end int) error {
+	if name == "" {
+		return fmt.Errorf("fork name is empty")
+	}
+	manifestPath := filepath.Join(dir, FIXTURE_MANIFEST)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	var manifest FixtureManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return fmt.Errorf("invalid fixture manifest %s, %v", manifestPath, err)
+	}
+
+	blocks, err := recordBlocks(source, dir, start, end, "-"+name)
+	if err != nil {
+		return err
+	}
+	forks := make([]*FixtureFork, 0, len(manifest.Forks)+1)
+	for _, fork := range manifest.Forks {
+		if fork.Name != name {
+			forks = append(forks, fork)
+		}
+	}
+	manifest.Forks = append(forks, &FixtureFork{Name: name, Blocks: blocks})
+	return saveFixtureManifest(dir, &manifest)
+}
+
+// 区块保存为 <height><suffix>.blk
+func recordBlocks(source BlockSource, dir string, start, end int, suffix string) ([]*FixtureBlockFile, error) {
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid block range %d-%d", start, end)
+	}
+
+	result := make([]*FixtureBlockFile, 0, end-start+1)
+	for height := start; height <= end; height++ {
+		hash, err := source.GetBlockHash(uint64(height))
+		if err != nil {
+			return nil, fmt.Errorf("GetBlockHash %d failed. %v", height, err)
+		}
+		raw, err := source.GetRawBlock(hash)
+		if err != nil {
+			return nil, fmt.Errorf("GetRawBlock %d %s failed. %v", height, hash, err)
+		}
+
+		file := &FixtureBlockFile{
+			Height: height,
+			Hash:   hash,
+			File:   fmt.Sprintf("%d%s.blk", height, suffix),
+		}
+		err = os.WriteFile(filepath.Join(dir, file.File), raw, 0644)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, file)
+		common.Log.Infof("RecordFixture: block %d %s saved", height, hash)
+	}
+	return result, nil
+}
+
+func saveFixtureManifest(dir string, manifest *FixtureManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FIXTURE_MANIFEST), data, 0644)
+}
@@ -0,0 +1,110 @@
+package base
+
+import (
+	"testing"
+)
+
+const reorgFixture = "../testdata/reorg"
+
+func TestFixtureSwitchFork(t *testing.T) {
+	source, err := LoadFixture(reorgFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, _ := source.GetBlockCount()
+	if count != 3 {
+		t.Fatalf("chain height %d, expected 3", count)
+	}
+	hash1, _ := source.GetBlockHash(1)
+	hash2, _ := source.GetBlockHash(2)
+
+	err = source.SwitchFork("reorg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, _ = source.GetBlockCount()
+	if count != 4 {
+		t.Fatalf("chain height %d after switch, expected 4", count)
+	}
+	if hash, _ := source.GetBlockHash(1); hash != hash1 {
+		t.Fatalf("block 1 changed after switch")
+	}
+	if hash, _ := source.GetBlockHash(2); hash == hash2 {
+		t.Fatalf("block 2 not replaced by fork")
+	}
+	// 被替换的区块仍然可以按hash读取
+	if _, err := source.GetRawBlock(hash2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFixtureSwitchForkGap(t *testing.T) {
+	source, err := LoadFixture(reorgFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fork := source.forks["reorg"]
+	source.forks["gap"] = map[int]*fixtureBlock{2: fork[2], 4: fork[4]}
+	source.forks["detached"] = map[int]*fixtureBlock{5: fork[4]}
+	source.forks["empty"] = map[int]*fixtureBlock{}
+
+	for _, name := range []string{"gap", "detached", "empty", "unknown"} {
+		if err := source.SwitchFork(name); err == nil {
+			t.Fatalf("switch to fork %s should fail", name)
+		}
+	}
+	// 失败时链不变
+	count, _ := source.GetBlockCount()
+	if count != 3 {
+		t.Fatalf("chain height %d after failed switch, expected 3", count)
+	}
+}
+
+func TestRecordFixtureFork(t *testing.T) {
+	source, err := LoadFixture(reorgFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = RecordFixture(source, dir, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordFixtureFork(source, dir, "", 2, 3); err == nil {
+		t.Fatalf("empty fork name should fail")
+	}
+
+	source.SwitchFork("reorg")
+	err = RecordFixtureFork(source, dir, "reorg", 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 再次录制同名分叉是替换
+	err = RecordFixtureFork(source, dir, "reorg", 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := LoadFixture(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.forks) != 1 {
+		t.Fatalf("%d forks recorded, expected 1", len(replay.forks))
+	}
+	count, _ := replay.GetBlockCount()
+	if count != 3 {
+		t.Fatalf("recorded chain height %d, expected 3", count)
+	}
+	err = replay.SwitchFork("reorg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for height := uint64(0); height <= 4; height++ {
+		expected, _ := source.GetBlockHash(height)
+		hash, _ := replay.GetBlockHash(height)
+		if hash != expected {
+			t.Fatalf("block %d is %s, expected %s", height, hash, expected)
+		}
+	}
+}
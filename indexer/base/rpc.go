@@ -1,6 +1,7 @@
 package base
 
 import (
+	"encoding/hex"
 	"time"
 
 	"github.com/OLProtocol/ordx/common"
@@ -58,3 +59,22 @@ func getRawBlock(blockHash string) (string, error) {
 	}
 	return h, err
 }
+
+// 通过bitcoind的json-rpc获取区块，失败时重试
+type RpcBlockSource struct{}
+
+func (s *RpcBlockSource) GetBlockCount() (uint64, error) {
+	return getBlockCount()
+}
+
+func (s *RpcBlockSource) GetBlockHash(height uint64) (string, error) {
+	return getBlockHash(height)
+}
+
+func (s *RpcBlockSource) GetRawBlock(blockHash string) ([]byte, error) {
+	rawBlock, err := getRawBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(rawBlock)
+}
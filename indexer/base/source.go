@@ -0,0 +1,9 @@
+package base
+
+// 区块数据来源，可以是bitcoind，也可以是esplora这样的REST服务
+type BlockSource interface {
+	GetBlockCount() (uint64, error)
+	GetBlockHash(height uint64) (string, error)
+	// 序列化后的区块数据
+	GetRawBlock(blockHash string) ([]byte, error)
+}
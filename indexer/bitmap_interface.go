@@ -0,0 +1,53 @@
+package indexer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/OLProtocol/ordx/indexer/protocol"
+)
+
+type BitmapInfo struct {
+	Height        int    `json:"height"`
+	InscriptionId string `json:"inscriptionId"`
+	Owner         string `json:"owner"`
+	Location      string `json:"location"` // txid:vout
+}
+
+// 区块高度对应的district，没有被铭刻时返回nil
+func (b *IndexerMgr) GetBitmap(height int) *BitmapInfo {
+	reg := b.ns.GetNameRegisterInfo(protocol.GetBitmapName(height))
+	if reg == nil {
+		return nil
+	}
+	return &BitmapInfo{
+		Height:        height,
+		InscriptionId: reg.Nft.Base.InscriptionId,
+		Owner:         reg.Owner,
+		Location:      reg.Location,
+	}
+}
+
+// 地址持有的district，按高度排序
+func (b *IndexerMgr) GetBitmapsByHolder(address string) []*BitmapInfo {
+	heights := make([]int, 0)
+	for _, name := range b.ns.GetNamesByOwner(address) {
+		if !strings.HasSuffix(name, "."+protocol.BITMAP_NAMESPACE) {
+			continue
+		}
+		height, ok := protocol.ParseBitmap([]byte(name))
+		if ok {
+			heights = append(heights, height)
+		}
+	}
+	sort.Ints(heights)
+
+	result := make([]*BitmapInfo, 0, len(heights))
+	for _, height := range heights {
+		info := b.GetBitmap(height)
+		if info != nil {
+			result = append(result, info)
+		}
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package indexer
+
+import (
+	"github.com/OLProtocol/ordx/indexer/protocol"
+)
+
+type TickerInfo struct {
+	Ticker        string `json:"ticker"`
+	Max           string `json:"max"`
+	Limit         string `json:"limit"`
+	Decimal       int    `json:"decimal"`
+	SelfMint      bool   `json:"selfMint"`
+	InscriptionId string `json:"inscriptionId"`
+	Deployer      string `json:"deployer"`
+	Height        int    `json:"height"`
+}
+
+// brc-20 ticker的部署信息，不区分大小写，没有部署时返回nil
+func (b *IndexerMgr) GetTickerInfo(ticker string) *TickerInfo {
+	t := b.ns.GetTicker(protocol.GetBrc20Ticker(ticker))
+	if t == nil {
+		return nil
+	}
+	return &TickerInfo{
+		Ticker:        t.OriginalTicker,
+		Max:           t.Max,
+		Limit:         t.Limit,
+		Decimal:       t.Decimal,
+		SelfMint:      t.SelfMint,
+		InscriptionId: t.InscriptionId,
+		Deployer:      t.Deployer,
+		Height:        t.Height,
+	}
+}
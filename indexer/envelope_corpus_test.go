@@ -0,0 +1,219 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/protocol"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// go test ./indexer -run TestEnvelopeCorpus -update 用当前的解析结果重新生成expected，
+// 链上的条目已经和ord核对过的不会被改写
+var updateCorpus = flag.Bool("update", false, "rewrite expected results in testdata/envelope_corpus.json and testdata/inspect_tx.json")
+
+const envelopeCorpusFile = "testdata/envelope_corpus.json"
+
+const CORPUS_SOURCE_SYNTHETIC = "synthetic"
+
+// synthetic的witness是构造的，覆盖ord信封规则中容易出错的情况，expected只说明解析结果没有变化。
+// 链上的条目source为mainnet或testnet，保存完整的交易(getrawtransaction的结果)和输入的序号，
+// txid可以为空，-update时根据交易计算。expected需要和ord decode --txid的结果核对，
+// 核对以后在checked中记录使用的ord版本，没有核对的条目测试失败
+type corpusEntry struct {
+	Name        string          `json:"name"`
+	Source      string          `json:"source"`
+	Description string          `json:"description"`
+	Height      int             `json:"height"`
+	Txid        string          `json:"txid,omitempty"`
+	Input       int             `json:"input,omitempty"`
+	Tx          string          `json:"tx,omitempty"`
+	Witness     []string        `json:"witness,omitempty"`
+	Checked     string          `json:"checked,omitempty"`
+	Expected    *corpusExpected `json:"expected"`
+}
+
+type corpusExpected struct {
+	Error     string            `json:"error,omitempty"`
+	Envelopes []*corpusEnvelope `json:"envelopes"`
+}
+
+type corpusEnvelope struct {
+	Offset          int             `json:"offset"`
+	ContentType     string          `json:"content_type,omitempty"`
+	ContentEncoding string          `json:"content_encoding,omitempty"`
+	Content         string          `json:"content,omitempty"` // 不是utf8时以hex:开头
+	DecodeError     string          `json:"decode_error,omitempty"`
+	Pointer         string          `json:"pointer,omitempty"`
+	Parents         []string        `json:"parents,omitempty"`
+	Delegate        string          `json:"delegate,omitempty"`
+	Metaprotocol    string          `json:"metaprotocol,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	Unrecognized    []string        `json:"unrecognized,omitempty"` // tag=value，都是hex
+	Stutter         bool            `json:"stutter,omitempty"`
+	Curse           string          `json:"curse,omitempty"` // 在第一个输入中，所在的聪上没有其他铭文
+	Protocol        string          `json:"protocol,omitempty"`
+	Name            string          `json:"name,omitempty"` // 通过名字规则检查后要注册的名字
+}
+
+func newCorpusIndexerMgr() *IndexerMgr {
+	return &IndexerMgr{
+		nameNormForm:   common.NORM_NFKC,
+		confusableMode: common.CONFUSABLE_FLAG,
+		confusables:    common.NewConfusableTable(),
+		nameLenPolicy:  common.NewNameLenPolicy(),
+		restrictions:   common.NewNameRestrictions(),
+		protocols:      protocol.NewDefaultRegistry(),
+	}
+}
+
+func corpusText(data []byte) string {
+	if data == nil {
+		return ""
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return "hex:" + hex.EncodeToString(data)
+}
+
+// 链上的条目从交易中取witness，同时检查txid
+func corpusWitness(t *testing.T, entry *corpusEntry) [][]byte {
+	if entry.Source == CORPUS_SOURCE_SYNTHETIC {
+		witness := make([][]byte, 0, len(entry.Witness))
+		for _, item := range entry.Witness {
+			data, err := hex.DecodeString(item)
+			if err != nil {
+				t.Fatalf("%s: invalid witness hex: %v", entry.Name, err)
+			}
+			witness = append(witness, data)
+		}
+		return witness
+	}
+
+	raw, err := hex.DecodeString(entry.Tx)
+	if err != nil || len(raw) == 0 {
+		t.Fatalf("%s: %s entry needs the raw transaction", entry.Name, entry.Source)
+	}
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		t.Fatalf("%s: invalid transaction: %v", entry.Name, err)
+	}
+	txid := tx.TxHash().String()
+	if entry.Txid == "" && *updateCorpus {
+		entry.Txid = txid
+	}
+	if entry.Txid == "" {
+		t.Fatalf("%s: no txid, run with -update", entry.Name)
+	}
+	if entry.Txid != txid {
+		t.Fatalf("%s: txid %s, transaction hash is %s", entry.Name, entry.Txid, txid)
+	}
+	if entry.Input < 0 || entry.Input >= len(tx.TxIn) {
+		t.Fatalf("%s: transaction has no input %d", entry.Name, entry.Input)
+	}
+	return tx.TxIn[entry.Input].Witness
+}
+
+func parseCorpusEntry(t *testing.T, s *IndexerMgr, entry *corpusEntry) *corpusExpected {
+	witness := corpusWitness(t, entry)
+
+	result := &corpusExpected{Envelopes: make([]*corpusEnvelope, 0)}
+	inscriptions, err := common.ParseEnvelopes(witness)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, insc := range inscriptions {
+		env := &corpusEnvelope{
+			Offset:          insc.Offset,
+			ContentType:     string(insc.ContentType),
+			ContentEncoding: string(insc.ContentEncoding),
+			Content:         corpusText(insc.Content),
+			Metaprotocol:    string(insc.Metaprotocol),
+			Parents:         insc.GetParents(),
+			Stutter:         insc.Stutter,
+			Curse:           common.GetCurse(insc, 0, nil),
+		}
+		if insc.DecodeError != nil {
+			env.DecodeError = insc.DecodeError.Error()
+		}
+		if insc.Pointer != nil {
+			env.Pointer = hex.EncodeToString(insc.Pointer)
+		}
+		if insc.Delegate != nil {
+			env.Delegate, _ = insc.GetDelegate()
+		}
+		if insc.Metadata != nil {
+			metadata, err := common.Cbor2json(insc.Metadata)
+			if err != nil {
+				env.Metadata = json.RawMessage(`"invalid"`)
+			} else {
+				env.Metadata = metadata
+			}
+		}
+		for _, field := range insc.Unrecognized {
+			env.Unrecognized = append(env.Unrecognized,
+				hex.EncodeToString(field.Tag)+"="+hex.EncodeToString(field.Value))
+		}
+		fields := insc.Fields()
+		env.Protocol = newEnvelope(fields, nil).Protocol
+		env.Name = s.parseNameAtHeight(fields, entry.Height)
+		result.Envelopes = append(result.Envelopes, env)
+	}
+	return result
+}
+
+func TestEnvelopeCorpus(t *testing.T) {
+	data, err := os.ReadFile(envelopeCorpusFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*corpusEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newCorpusIndexerMgr()
+	for _, entry := range entries {
+		got := parseCorpusEntry(t, s, entry)
+		onChain := entry.Source != CORPUS_SOURCE_SYNTHETIC
+		if *updateCorpus && !(onChain && entry.Checked != "") {
+			entry.Expected = got
+			continue
+		}
+		if entry.Expected == nil {
+			t.Errorf("%s: no expected result, run with -update", entry.Name)
+			continue
+		}
+		if onChain && entry.Checked == "" {
+			t.Errorf("%s: expected result of %s is not checked against ord", entry.Name, entry.Txid)
+		}
+		// 通过json比较，metadata的格式不影响结果
+		gotJson, _ := json.Marshal(got)
+		wantJson, _ := json.Marshal(entry.Expected)
+		var gotValue, wantValue interface{}
+		json.Unmarshal(gotJson, &gotValue)
+		json.Unmarshal(wantJson, &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("%s (%s):\n got  %s\n want %s", entry.Name, entry.Description, gotJson, wantJson)
+		}
+	}
+
+	if *updateCorpus {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(envelopeCorpusFile, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
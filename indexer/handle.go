@@ -1,14 +1,33 @@
 package indexer
 
 import (
+	"bytes"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/OLProtocol/ordx/common"
+	base_indexer "github.com/OLProtocol/ordx/indexer/base"
 	"github.com/OLProtocol/ordx/indexer/ns"
+	"github.com/OLProtocol/ordx/indexer/protocol"
+	"github.com/btcsuite/btcd/wire"
 )
 
+const (
+	MAX_STORED_CONTENT_SIZE = 4096 // 更大的内容不能通过delegate用于协议
+	MAX_DELEGATE_DEPTH      = 8
+	DELEGATE_READ_RETRIES   = 5
+)
+
+// 读取delegate内容失败时，第n次重试之前等待n倍的时间
+var delegateRetryDelay = time.Second
+
 func (s *IndexerMgr) processOrdProtocol(block *common.Block) {
+	if s.mempool != nil {
+		s.mempool.RemoveConfirmed(block)
+	}
+
 	if block.Height < s.ordFirstHeight {
 		return
 	}
@@ -17,20 +36,41 @@ func (s *IndexerMgr) processOrdProtocol(block *common.Block) {
 	measureStartTime := time.Now()
 	count := 0
 	for _, tx := range block.Transactions {
-		id := 0
-		for _, input := range tx.Inputs {
+		// 先处理名字的转移，同一个交易中的铭文使用转移后的持有者
+		s.handleNameTransfer(tx)
+		spent := s.takeInscriptions(tx)
+		sat := s.takeInscribedSat(tx, spent)
+		created := make(map[string][]string)
 
-			inscriptions, err := common.ParseInscription(input.Witness)
-			if err != nil {
-				continue
-			}
+		id := 0
+		for i, input := range tx.Inputs {
 
-			for _, insc := range inscriptions {
-				s.handleOrd(insc)
+			for _, insc := range input.Inscriptions {
+				if id == 0 {
+					// 同一个交易中后面的铭文可能使用前面的铭文作为delegate
+					s.ns.AddReveal(tx.Txid, block.Height)
+				}
+				fields := insc.Fields()
+				if insc.DecodeError != nil {
+					common.Log.Warnf("%si%d decode content failed. %v", tx.Txid, id, insc.DecodeError)
+				}
+				delegate := s.handleDelegate(insc, fmt.Sprintf("%si%d", tx.Txid, id), fields)
+				nft := newNft(insc, tx.Txid, id, block)
+				nft.Base.Delegate = delegate
+				offset, exact := getInscriptionOffset(insc, i, tx)
+				sat = s.numberInscription(insc, i, offset == 0 && exact, nft, sat)
+				parents := s.handleParents(insc, nft, spent)
+				txCtx := &protocol.TxContext{Block: block, Tx: tx, InputIndex: i, Index: id, Offset: offset}
+				if _, location := txCtx.Receiver(); location != "" {
+					created[location] = append(created[location], nft.Base.InscriptionId)
+				}
+				s.handleOrd(fields, nft, parents, txCtx)
 				id++
 				count++
 			}
 		}
+		s.putInscribedSat(tx, sat)
+		s.putInscriptions(tx, spent, created)
 		if id > 0 {
 			detectOrdMap[tx.Txid] = id
 		}
@@ -38,94 +78,614 @@ func (s *IndexerMgr) processOrdProtocol(block *common.Block) {
 	common.Log.Infof("processOrdProtocol %d,is done: cost: %v", block.Height, time.Since(measureStartTime))
 }
 
-func (s *IndexerMgr) handleNameRegister(content *common.OrdxRegContent, nft *common.Nft) {
+// 保存链上的原始内容和它的ContentEncoding，解码后的内容只用于识别协议
+func newNft(insc *common.Inscription, txid string, index int, block *common.Block) *common.Nft {
+	return &common.Nft{
+		Base: &common.InscribeBaseContent{
+			InscriptionId:   fmt.Sprintf("%si%d", txid, index),
+			BlockHeight:     int32(block.Height),
+			BlockTime:       block.Timestamp.Unix(),
+			ContentType:     insc.ContentType,
+			Content:         insc.Body,
+			MetaProtocol:    insc.Metaprotocol,
+			MetaData:        insc.Metadata,
+			ContentEncoding: insc.ContentEncoding,
+			Id:              common.INVALID_INSCRIPTION_NUM, // 在numberInscription中分配
+		},
+	}
+}
 
-	name := strings.ToLower(content.Name)
+// 交易第一个聪上已有的铭文，其他输入上记录的聪无法继续跟踪。
+// 记录了聪的输出一定也记录了铭文，所以只需要检查spent中有铭文的输入
+func (s *IndexerMgr) takeInscribedSat(tx *common.Transaction, spent [][]string) *common.SatInscriptions {
+	var result *common.SatInscriptions
+	for i, ids := range spent {
+		if len(ids) == 0 {
+			continue
+		}
+		input := tx.Inputs[i]
+		location := fmt.Sprintf("%s:%d", input.Txid, input.Vout)
+		if i == 0 {
+			result = s.ns.GetInscribedSat(location)
+		}
+		s.ns.MoveInscribedSat(location, "", nil)
+	}
+	return result
+}
 
-	reg := &ns.NameRegister{
-		Nft:  nft,
-		Name: name,
+// 交易第一个聪转移到第一个非零输出的第一个聪
+func (s *IndexerMgr) putInscribedSat(tx *common.Transaction, sat *common.SatInscriptions) {
+	if sat == nil {
+		return
 	}
-	nft.Base.TypeName = common.ASSET_TYPE_NS
-	nft.Base.UserData = []byte(name)
+	output := getTransferOutput(tx, 0)
+	if output == nil {
+		return
+	}
+	s.ns.MoveInscribedSat("", fmt.Sprintf("%s:%d", tx.Txid, output.N), sat)
+}
 
-	s.ns.NameRegister(reg)
+// 铭文在交易输出中的偏移，pointer超出输出的总金额时使用默认位置，即所在输入的第一个聪。
+// 没有输入的金额，只有第一个输入的默认位置是准确的，其他输入返回0，exact为false
+func getInscriptionOffset(insc *common.Inscription, inputIndex int, tx *common.Transaction) (offset uint64, exact bool) {
+	pointer, ok := insc.GetPointer()
+	if ok && pointer < protocol.GetTotalOutputValue(tx) {
+		return pointer, true
+	}
+	return 0, inputIndex == 0
 }
 
-func (s *IndexerMgr) handleNameRouting(content *common.OrdxUpdateContentV2, nft *common.Nft) {
-	content.Name = strings.ToLower(content.Name)
-	reg := s.ns.GetNameRegisterInfo(content.Name)
-	if reg == nil {
-		common.Log.Warnf("IndexerMgr.handleNameRouting: %s, Name %s not exist", nft.Base.InscriptionId, content.Name)
-		return
+// 按照ord的规则判断是否被诅咒并分配编号，返回交易第一个聪上的铭文。
+// 编号不保证和ord一致：没有输入的金额，只跟踪交易第一个聪上已有的铭文，
+// 其他聪上的重复铭刻不会被诅咒，jubilee之前会使用正数编号并影响之后所有的编号
+func (s *IndexerMgr) numberInscription(insc *common.Inscription, inputIndex int, onFirstSat bool, nft *common.Nft,
+	sat *common.SatInscriptions) *common.SatInscriptions {
+	var inscribed *common.SatInscriptions
+	if onFirstSat {
+		inscribed = sat
 	}
+	curse := common.GetCurse(insc, inputIndex, inscribed)
+	jubilant := int(nft.Base.BlockHeight) >= s.jubileeHeight
+	nft.Base.Curse = curse
+	nft.Base.Vindicated = curse != common.CURSE_NONE && jubilant
+	nft.Base.Id = s.ns.NewInscriptionNumber(curse != common.CURSE_NONE && !jubilant)
 
-	// TODO
-	// 只需要当前owner持有该nft就可以修改，而不必在sat上继续铸造
-	// if nft.OwnerAddressId != reg.Nft.OwnerAddressId {
-	// 	common.Log.Warnf("IndexerMgr.handleNameRouting: %s, Name %s has different owner", nft.Base.InscriptionId, content.Name)
-	// 	return
-	// }
+	if !onFirstSat {
+		return sat
+	}
+	if sat == nil {
+		sat = &common.SatInscriptions{FirstCursedOrVindicated: curse != common.CURSE_NONE}
+	} else {
+		sat = &common.SatInscriptions{Count: sat.Count, FirstCursedOrVindicated: sat.FirstCursedOrVindicated}
+	}
+	sat.Count++
+	return sat
+}
 
+// 有delegate时fields中使用delegate的内容，返回delegate
+func (s *IndexerMgr) handleDelegate(insc *common.Inscription, inscriptionId string, fields map[int][]byte) string {
+	delegate, ok := insc.GetDelegate()
+	if !ok {
+		return ""
+	}
+	delete(fields, common.FIELD_CONTENT)
+	delete(fields, common.FIELD_CONTENT_TYPE)
+	resolved := s.resolveDelegate(inscriptionId, delegate, s.getDelegateContent)
+	if resolved != nil {
+		setField(fields, common.FIELD_CONTENT, resolved.DecodedContent())
+		setField(fields, common.FIELD_CONTENT_TYPE, resolved.ContentType)
+	}
+	return delegate
 }
 
-func (s *IndexerMgr) handleOrd(fields map[int][]byte) {
+func newInscriptionContent(insc *common.Inscription, inscriptionId string) *ns.InscriptionContent {
+	content := &ns.InscriptionContent{
+		InscriptionId:   inscriptionId,
+		ContentType:     insc.ContentType,
+		ContentEncoding: insc.ContentEncoding,
+	}
+	if len(insc.Body) <= MAX_STORED_CONTENT_SIZE && len(insc.Content) <= MAX_STORED_CONTENT_SIZE {
+		content.Body = insc.Body
+		if len(insc.ContentEncoding) > 0 {
+			content.Content = insc.Content
+		}
+	} else {
+		content.Truncated = true
+	}
+	content.Delegate, _ = insc.GetDelegate()
+	return content
+}
+
+func setField(fields map[int][]byte, tag int, value []byte) {
+	if value != nil {
+		fields[tag] = value
+	}
+}
 
-	var nft *common.Nft
-	protocol, content := common.GetProtocol(fields)
-	switch protocol {
-	case "sns":
-		domain := common.ParseDomainContent(string(fields[common.FIELD_CONTENT]))
-		if domain == nil {
-			domain = common.ParseDomainContent(string(content))
+// delegate最终指向的铭文内容，不存在或者有循环时返回nil
+func (s *IndexerMgr) resolveDelegate(inscriptionId, delegate string,
+	getContent func(string) *ns.InscriptionContent) *ns.InscriptionContent {
+	visited := map[string]bool{inscriptionId: true}
+	for i := 0; i < MAX_DELEGATE_DEPTH; i++ {
+		if visited[delegate] {
+			common.Log.Warnf("%s delegate %s is a cycle", inscriptionId, delegate)
+			return nil
+		}
+		visited[delegate] = true
+		content := getContent(delegate)
+		if content == nil {
+			common.Log.Warnf("%s delegate %s not exist", inscriptionId, delegate)
+			return nil
+		}
+		if content.Delegate == "" {
+			return content
+		}
+		delegate = content.Delegate
+	}
+	common.Log.Warnf("%s delegate %s is too deep", inscriptionId, delegate)
+	return nil
+}
+
+// 索引时使用：第一次被引用时从区块读取，之后使用保存的内容。
+// 读取失败时重试，一直失败时结果不确定，不能继续索引
+func (s *IndexerMgr) getDelegateContent(inscriptionId string) *ns.InscriptionContent {
+	content := s.ns.GetContent(inscriptionId)
+	if content != nil {
+		return content
+	}
+	var err error
+	for n := 1; n <= DELEGATE_READ_RETRIES; n++ {
+		content, err = s.readInscriptionContent(inscriptionId)
+		if err == nil {
+			break
 		}
-		if domain != nil {
-			switch domain.Op {
-			case "reg":
-				s.handleSnsName(domain.Name, nft)
+		if n < DELEGATE_READ_RETRIES {
+			common.Log.Infof("read delegate %s failed, try again ... %v", inscriptionId, err)
+			time.Sleep(time.Duration(n) * delegateRetryDelay)
+		}
+	}
+	if err != nil {
+		common.Log.Panicf("read delegate %s failed. %v", inscriptionId, err)
+	}
+	if content != nil {
+		s.ns.AddContent(content)
+	}
+	return content
+}
+
+// 从铭文所在的区块读取内容，铭文不存在时返回nil
+func (s *IndexerMgr) readInscriptionContent(inscriptionId string) (*ns.InscriptionContent, error) {
+	sep := strings.LastIndex(inscriptionId, "i")
+	if sep < 0 {
+		return nil, nil
+	}
+	txid := inscriptionId[:sep]
+	index, err := strconv.Atoi(inscriptionId[sep+1:])
+	if err != nil {
+		return nil, nil
+	}
+	height, ok := s.ns.GetRevealHeight(txid)
+	if !ok {
+		return nil, nil
+	}
+
+	source := s.blockSource
+	if source == nil {
+		source = &base_indexer.RpcBlockSource{}
+	}
+	hash, err := source.GetBlockHash(uint64(height))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := source.GetRawBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	var block wire.MsgBlock
+	err = block.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range block.Transactions {
+		if tx.TxHash().String() != txid {
+			continue
+		}
+		// 和processOrdProtocol一样按输入的顺序编号
+		id := 0
+		for _, input := range tx.TxIn {
+			if len(input.Witness) == 0 {
+				continue
+			}
+			inscriptions, err := common.ParseEnvelopes(input.Witness)
+			if err != nil {
+				continue
+			}
+			if index < id+len(inscriptions) {
+				return newInscriptionContent(inscriptions[index-id], inscriptionId), nil
 			}
+			id += len(inscriptions)
 		}
-	case "brc-20":
-		brc20Content := common.ParseBrc20Content(string(fields[common.FIELD_CONTENT]))
-		if brc20Content != nil {
-			switch brc20Content.Op {
-			case "deploy":
-				s.handleSnsName(brc20Content.Ticker, nft)
+		return nil, nil
+	}
+	return nil, fmt.Errorf("transaction %s not in block %d %s", txid, height, hash)
+}
+
+// 交易花费的输入中的铭文，按输入的序号。每个输入只查询这一次，聪和parent的检查都使用这个结果
+func (s *IndexerMgr) takeInscriptions(tx *common.Transaction) [][]string {
+	result := make([][]string, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		location := fmt.Sprintf("%s:%d", input.Txid, input.Vout)
+		ids := s.ns.GetInscriptionsAt(location)
+		if len(ids) == 0 {
+			continue
+		}
+		s.ns.MoveInscriptions(location, "", nil)
+		result[i] = ids
+	}
+	return result
+}
+
+// 输入中的铭文和handleNameTransfer一样转移，新的铭文在created中。
+// 没有输入的金额，除了交易第一个聪上的铭文，其他铭文可能和ord记录的输出不同
+func (s *IndexerMgr) putInscriptions(tx *common.Transaction, spent [][]string, created map[string][]string) {
+	for i, ids := range spent {
+		if len(ids) == 0 {
+			continue
+		}
+		output := getTransferOutput(tx, i)
+		if output == nil {
+			continue
+		}
+		location := fmt.Sprintf("%s:%d", tx.Txid, output.N)
+		created[location] = append(created[location], ids...)
+	}
+
+	// 按输出的顺序，保证写入的顺序是确定的
+	for _, output := range tx.Outputs {
+		location := fmt.Sprintf("%s:%d", tx.Txid, output.N)
+		ids, ok := created[location]
+		if ok {
+			s.ns.MoveInscriptions("", location, ids)
+		}
+	}
+}
+
+// parent需要在同一个交易中被花费，返回有效的parent。
+// parent的位置来自putInscriptions的近似转移，位置不准确时child会被当做无效
+func (s *IndexerMgr) handleParents(insc *common.Inscription, nft *common.Nft, spent [][]string) []string {
+	result := make([]string, 0)
+	visited := make(map[string]bool)
+	for _, parent := range insc.GetParents() {
+		if visited[parent] {
+			continue
+		}
+		visited[parent] = true
+		found := false
+		for _, ids := range spent {
+			for _, id := range ids {
+				if id == parent {
+					found = true
+				}
 			}
 		}
-	case "btcname":
-		commonContent := common.ParseCommonContent(string(fields[common.FIELD_CONTENT]))
-		if commonContent != nil {
-			switch commonContent.Op {
-			case "routing":
-				s.handleNameRouting(commonContent, nft)
+		if !found {
+			common.Log.Debugf("%s parent %s is not spent in the same tx", nft.Base.InscriptionId, parent)
+			continue
+		}
+		result = append(result, parent)
+		s.ns.AddChild(parent, nft.Base.InscriptionId)
+	}
+	if len(result) > 0 {
+		nft.Base.Parent = result[0]
+	}
+	return result
+}
+
+// 名字铭文所在的输出被花费
+func (s *IndexerMgr) handleNameTransfer(tx *common.Transaction) {
+	for i, input := range tx.Inputs {
+		location := fmt.Sprintf("%s:%d", input.Txid, input.Vout)
+		name := s.ns.GetNameByLocation(location)
+		if name == "" {
+			continue
+		}
+		output := getTransferOutput(tx, i)
+		if output == nil {
+			common.Log.Warnf("%s Name %s burned as fee", tx.Txid, name)
+			s.ns.Transfer(name, location, "", "")
+			continue
+		}
+		s.ns.Transfer(name, location, fmt.Sprintf("%s:%d", tx.Txid, output.N), protocol.GetOutputAddress(output))
+	}
+}
+
+// 区块中没有输入的金额，不能准确计算聪的位置：第一个输入的第一个聪一定在第一个非零输出中，
+// 其他输入假设和同样序号的输出对应
+func getTransferOutput(tx *common.Transaction, inputIndex int) *common.Output {
+	if inputIndex == 0 {
+		for _, output := range tx.Outputs {
+			if output.Value > 0 {
+				return output
 			}
 		}
-	default:
-		// 3. 如果content中的内容格式，符合 *.* 或者 * , 并且字段在32字节以内，符合名字规范，就把它当做一个名字来处理
-		// text/plain;charset=utf-8 abc
-		// 或者简单文本 xxx.xx 或者 xx
-		if protocol == "" {
-			s.handleSnsName(string(fields[common.FIELD_CONTENT]), nft)
+		return nil
+	}
+	if len(tx.Outputs) == 0 {
+		return nil
+	}
+	if inputIndex >= len(tx.Outputs) {
+		return tx.Outputs[len(tx.Outputs)-1]
+	}
+	return tx.Outputs[inputIndex]
+}
+
+// 只检查是否已经注册
+func (s *IndexerMgr) handleNamespaceName(name string, nft *common.Nft, tx *protocol.TxContext) bool {
+	info := s.ns.GetNameRegisterInfo(name)
+	if info != nil {
+		common.Log.Warnf("%s Name %s exist, registered at %s",
+			nft.Base.InscriptionId, name, info.Nft.Base.InscriptionId)
+		return false
+	}
+
+	regInfo := &common.OrdxRegContent{
+		OrdxBaseContent: common.OrdxBaseContent{P: "sns", Op: "reg"},
+		Name:            name}
+
+	s.handleNameRegister(regInfo, nft, tx, name, "")
+	return true
+}
+
+func (s *IndexerMgr) handleNameRegister(content *common.OrdxRegContent, nft *common.Nft, tx *protocol.TxContext, original, confusableWith string) {
+
+	name := strings.ToLower(content.Name)
+
+	owner, location := tx.Receiver()
+	reg := &ns.NameRegister{
+		Nft:            nft,
+		Name:           name,
+		Original:       original,
+		ConfusableWith: confusableWith,
+		Owner:          owner,
+		Location:       location,
+	}
+	if s.confusableMode != common.CONFUSABLE_OFF {
+		reg.Skeleton = s.confusables.Skeleton(name)
+	}
+	nft.Base.TypeName = common.ASSET_TYPE_NS
+	nft.Base.UserData = []byte(name)
+
+	s.ns.NameRegister(reg)
+}
+
+func (s *IndexerMgr) handleOrd(fields map[int][]byte, nft *common.Nft, parents []string, tx *protocol.TxContext) {
+	env := newEnvelope(fields, nft)
+	env.Parents = parents
+	handler := s.protocols.Get(env.Protocol, int(nft.Base.BlockHeight))
+	if handler == nil {
+		return
+	}
+	handler.Handle(env, tx, &protocolNameService{s: s, height: int(nft.Base.BlockHeight)})
+}
+
+func newEnvelope(fields map[int][]byte, nft *common.Nft) *protocol.Envelope {
+	name, content := common.GetProtocol(fields)
+	if name == protocol.PROTOCOL_TEXT {
+		// bitmap有自己的规则，不当做普通的名字
+		if _, ok := protocol.ParseBitmap(fields[common.FIELD_CONTENT]); ok {
+			name = protocol.PROTOCOL_BITMAP
+		}
+	}
+	return &protocol.Envelope{
+		Fields:   fields,
+		Protocol: name,
+		Content:  content,
+		Nft:      nft,
+	}
+}
+
+// 铭文会注册的名字，和handleSnsName使用同样的规则，不检查是否已经被注册
+func (s *IndexerMgr) parseNameToRegister(fields map[int][]byte) string {
+	return s.parseNameAtHeight(fields, s.nextHeight())
+}
+
+func (s *IndexerMgr) parseNameAtHeight(fields map[int][]byte, height int) string {
+	name, reason := s.checkNameAtHeight(newEnvelope(fields, nil), height)
+	if reason != "" {
+		return ""
+	}
+	return name
+}
+
+// 返回规范化后的名字和不能注册的原因(common.NAME_*)，不注册名字时都为空，
+// 不检查依赖数据库的规则，比如是否已经被注册
+func (s *IndexerMgr) checkNameAtHeight(env *protocol.Envelope, height int) (string, string) {
+	parser, ok := s.protocols.Get(env.Protocol, height).(protocol.NameParser)
+	if !ok {
+		return "", ""
+	}
+	name, ok := parser.ParseName(env)
+	if !ok {
+		return "", ""
+	}
+	// 和handleNamespaceName一样，有自己的规则
+	if env.Protocol == protocol.PROTOCOL_BITMAP {
+		return name, ""
+	}
+	name, reason, _ := s.checkSnsName(name, height)
+	return name, reason
+}
+
+// sns名字的规则，返回规范化后的名字，不能注册的原因(common.NAME_*)和被限制时的规则，
+// 不检查是否已经被注册
+func (s *IndexerMgr) checkSnsName(name string, height int) (string, string, *common.NameRestriction) {
+	name = s.normalizeNameAtHeight(name, height)
+	if reason := common.CheckSNSNameAtHeight(name, height, s.nameLenPolicy); reason != "" {
+		return name, reason, nil
+	}
+	// 专用的namespace只能通过对应的协议注册
+	if common.GetNameNamespace(name) == protocol.BITMAP_NAMESPACE &&
+		s.protocols.Get(protocol.PROTOCOL_BITMAP, height) != nil {
+		return name, common.NAME_NAMESPACE, nil
+	}
+	if restriction := s.getRestriction(name, height); restriction != nil {
+		return name, restriction.Type, restriction
+	}
+	return name, "", nil
+}
+
+// 注册和查询是否可以注册共用的检查，不包括内存池中的注册，Reason为空时可以注册
+func (s *IndexerMgr) checkSnsNameRegister(name string, height int) *NameAvailability {
+	result := &NameAvailability{}
+	result.Name, result.Reason, result.Restriction = s.checkSnsName(name, height)
+	if result.Reason != "" {
+		return result
+	}
+
+	reg := s.getNameRegister(name, height)
+	if reg != nil {
+		result.Reason = common.NAME_TAKEN
+		if reg.Nft != nil {
+			result.RegisteredBy = reg.Nft.Base.InscriptionId
 		}
+		return result
+	}
+
+	result.ConfusableWith = s.findConfusableName(result.Name)
+	if result.ConfusableWith != "" && s.confusableMode == common.CONFUSABLE_REJECT && height >= s.confusableHeight {
+		result.Reason = common.NAME_CONFUSABLE
 	}
+	return result
+}
 
+// 判断名字是否重复都基于规范化以后的名字，使用最新的规则
+func (s *IndexerMgr) normalizeName(name string) string {
+	return common.NormalizeName(common.PreprocessName(name), s.nameNormForm)
 }
 
-func (s *IndexerMgr) handleSnsName(name string, nft *common.Nft) {
-	name = common.PreprocessName(name)
-	if common.IsValidSNSName(name) {
-		info := s.ns.GetNameRegisterInfo(name)
-		if info != nil {
-			common.Log.Warnf("%s Name %s exist, registered at %s",
-				nft.Base.InscriptionId, name, info.Nft.Base.InscriptionId)
-			return
+// 规范化在生效高度之前不改变历史上的注册结果
+func (s *IndexerMgr) normalizeNameAtHeight(name string, height int) string {
+	if height < s.nameNormHeight {
+		return common.NormalizeName(common.PreprocessName(name), common.NORM_NONE)
+	}
+	return s.normalizeName(name)
+}
+
+// name是normalizeNameAtHeight规范化后的名字，使用同样规则规范化的限制
+func (s *IndexerMgr) getRestriction(name string, height int) *common.NameRestriction {
+	if height < s.nameNormHeight {
+		return s.oldRestrictions.Get(name, height)
+	}
+	return s.restrictions.Get(name, height)
+}
+
+// 生效高度之前注册的名字没有规范化，规范化以后找不到时再按原来的形式查找
+func (s *IndexerMgr) getNameRegister(name string, height int) *ns.NameRegister {
+	key := s.normalizeNameAtHeight(name, height)
+	reg := s.ns.GetNameRegisterInfo(key)
+	if reg == nil && s.nameNormHeight > 0 && height >= s.nameNormHeight {
+		legacy := common.NormalizeName(common.PreprocessName(name), common.NORM_NONE)
+		if legacy != key {
+			reg = s.ns.GetNameRegisterInfo(legacy)
 		}
+	}
+	return reg
+}
 
-		regInfo := &common.OrdxRegContent{
-			OrdxBaseContent: common.OrdxBaseContent{P: "sns", Op: "reg"},
-			Name:            name}
+// 返回和name形似的已注册名字，没有时返回空。
+// 每个skeleton只索引第一个注册的名字，所以总是返回最早的那个
+func (s *IndexerMgr) findConfusableName(name string) string {
+	if s.confusableMode == common.CONFUSABLE_OFF {
+		return ""
+	}
+	other := s.ns.GetNameBySkeleton(s.confusables.Skeleton(name))
+	if other == name {
+		return ""
+	}
+	return other
+}
 
-		s.handleNameRegister(regInfo, nft)
+func (s *IndexerMgr) handleSnsName(name string, nft *common.Nft, tx *protocol.TxContext) bool {
+	original := common.PreprocessName(name)
+	check := s.checkSnsNameRegister(original, int(nft.Base.BlockHeight))
+	name = check.Name
+	switch {
+	case check.Restriction != nil:
+		common.Log.Warnf("%s Name %s is %s, %s",
+			nft.Base.InscriptionId, name, check.Restriction.Type, check.Restriction.Reason)
+	case check.Reason == common.NAME_NAMESPACE:
+		common.Log.Warnf("%s Name %s is in namespace %s", nft.Base.InscriptionId, name, protocol.BITMAP_NAMESPACE)
+	case check.Reason == common.NAME_TAKEN:
+		common.Log.Warnf("%s Name %s exist, registered at %s",
+			nft.Base.InscriptionId, name, check.RegisteredBy)
+	case check.Reason == common.NAME_CONFUSABLE:
+		common.Log.Warnf("%s Name %s is confusable with %s",
+			nft.Base.InscriptionId, name, check.ConfusableWith)
+	case check.ConfusableWith != "":
+		common.Log.Infof("%s Name %s is confusable with %s",
+			nft.Base.InscriptionId, name, check.ConfusableWith)
 	}
+	if check.Reason != "" {
+		return false
+	}
+
+	regInfo := &common.OrdxRegContent{
+		OrdxBaseContent: common.OrdxBaseContent{P: "sns", Op: "reg"},
+		Name:            name}
+
+	s.handleNameRegister(regInfo, nft, tx, original, check.ConfusableWith)
+	return true
+}
+
+// 提供给协议处理器的名字服务
+type protocolNameService struct {
+	s      *IndexerMgr
+	height int // 正在处理的铭文所在的高度
+}
+
+func (p *protocolNameService) RegisterName(name string, nft *common.Nft, tx *protocol.TxContext) bool {
+	return p.s.handleSnsName(name, nft, tx)
+}
+
+func (p *protocolNameService) RegisterNamespaceName(name string, nft *common.Nft, tx *protocol.TxContext) bool {
+	return p.s.handleNamespaceName(name, nft, tx)
+}
+
+func (p *protocolNameService) GetNameRegister(name string) *ns.NameRegister {
+	return p.s.getNameRegister(name, p.height)
+}
+
+// 已注册的名字使用注册时的形式
+func (p *protocolNameService) nameKey(name string) string {
+	if reg := p.GetNameRegister(name); reg != nil {
+		return reg.Name
+	}
+	return p.s.normalizeNameAtHeight(name, p.height)
+}
+
+func (p *protocolNameService) SetKeyValues(name string, kvs map[string]string, nft *common.Nft) {
+	name = p.nameKey(name)
+	for k, v := range kvs {
+		p.s.ns.SetKeyValue(name, k, v, nft.Base.InscriptionId)
+	}
+}
+
+func (p *protocolNameService) SetPrimaryName(address, name string, nft *common.Nft) {
+	p.s.ns.SetPrimaryName(address, p.nameKey(name), nft.Base.InscriptionId)
+}
+
+func (p *protocolNameService) DeployTicker(ticker *ns.Brc20Ticker) bool {
+	info := p.s.ns.GetTicker(ticker.Ticker)
+	if info != nil {
+		common.Log.Warnf("%s Ticker %s exist, deployed at %s",
+			ticker.InscriptionId, ticker.OriginalTicker, info.InscriptionId)
+		return false
+	}
+	p.s.ns.DeployTicker(ticker)
+	return true
+}
+
+func (p *protocolNameService) GetTicker(ticker string) *ns.Brc20Ticker {
+	return p.s.ns.GetTicker(protocol.GetBrc20Ticker(ticker))
 }
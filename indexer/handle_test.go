@@ -0,0 +1,582 @@
+package indexer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/OLProtocol/ordx/common"
+	base_indexer "github.com/OLProtocol/ordx/indexer/base"
+	"github.com/OLProtocol/ordx/indexer/protocol"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const testOwner = "bc1ptestowner"
+
+// 铭文在交易的第一个输出，height同时是交易的序号
+func testTxContext(height int, owner string) (*common.Nft, *protocol.TxContext) {
+	txid := fmt.Sprintf("%064x", height)
+	nft := &common.Nft{Base: &common.InscribeBaseContent{
+		InscriptionId: txid + "i0",
+		BlockHeight:   int32(height),
+	}}
+	tx := &common.Transaction{
+		Txid: txid,
+		Outputs: []*common.Output{{
+			Height:  height,
+			Value:   10000,
+			Address: &common.ScriptPubKey{Addresses: []string{owner}},
+		}},
+	}
+	return nft, &protocol.TxContext{Block: &common.Block{Height: height}, Tx: tx}
+}
+
+func registerTestName(mgr *IndexerMgr, name string, height int) bool {
+	nft, tx := testTxContext(height, testOwner)
+	return mgr.handleSnsName(name, nft, tx)
+}
+
+func TestNormalizationDefaultOff(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	if !registerTestName(mgr, "foo.sats", 10) || !registerTestName(mgr, "ｆｏｏ.sats", 11) {
+		t.Fatalf("full-width name should not collide without normalization")
+	}
+	if registerTestName(mgr, "FOO.sats", 12) {
+		t.Fatalf("names are still case insensitive")
+	}
+}
+
+func TestNormalizationHeight(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	mgr.WithNameNormalization(common.NORM_NFKC, 100)
+
+	// 生效之前和原来一样只转小写
+	for i, name := range []string{"foo.sats", "ｆｏｏ.sats", "ｂａｚ.sats"} {
+		if !registerTestName(mgr, name, 50+i) {
+			t.Fatalf("%s should be registered before activation", name)
+		}
+	}
+	if reg := mgr.ns.GetNameRegisterInfo("ｆｏｏ.sats"); reg == nil || reg.Name != "ｆｏｏ.sats" {
+		t.Fatalf("name registered before activation is normalized")
+	}
+
+	// 生效以后，和规范化的名字或者生效前注册的原名冲突
+	for i, name := range []string{"ｆｏｏ.sats", "ｂａｚ.sats", "ＦＯＯ.sats"} {
+		if registerTestName(mgr, name, 100+i) {
+			t.Fatalf("%s should collide after activation", name)
+		}
+	}
+	if !registerTestName(mgr, "ｑｕｘ.sats", 110) {
+		t.Fatalf("new name should be registered after activation")
+	}
+	if reg := mgr.ns.GetNameRegisterInfo("qux.sats"); reg == nil {
+		t.Fatalf("name registered after activation is not normalized")
+	}
+	if registerTestName(mgr, "qux.sats", 111) {
+		t.Fatalf("qux.sats should collide with ｑｕｘ.sats")
+	}
+
+	// 生效以后按名字查询也能找到生效前注册的原名
+	if reg := mgr.getNameRegister("ｂａｚ.sats", 200); reg == nil || reg.Name != "ｂａｚ.sats" {
+		t.Fatalf("legacy name not found after activation")
+	}
+}
+
+// 名字在同一个交易中先转移，再处理修改的铭文
+func TestNameUpdateRequiresSpendingName(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	if !registerTestName(mgr, "alpha.sats", 10) {
+		t.Fatalf("alpha.sats not registered")
+	}
+	update := func(height int, url string, spent ...string) {
+		nft, tx := testTxContext(height, testOwner)
+		for _, txid := range spent {
+			tx.Tx.Inputs = append(tx.Tx.Inputs, &common.Input{Txid: txid})
+		}
+		fields := map[int][]byte{common.FIELD_CONTENT: []byte(
+			`{"p":"sns","op":"update","name":"alpha.sats","url":"` + url + `"}`)}
+		mgr.handleNameTransfer(tx.Tx)
+		mgr.handleOrd(fields, nft, nil, tx)
+	}
+
+	// 其他人铸造到持有者的地址
+	update(11, "https://evil", fmt.Sprintf("%064x", 99))
+	if kvs := mgr.ns.GetKeyValues("alpha.sats"); kvs["url"] != nil {
+		t.Fatalf("alpha.sats updated without spending the name")
+	}
+
+	update(12, "https://a.b", fmt.Sprintf("%064x", 10))
+	if kvs := mgr.ns.GetKeyValues("alpha.sats"); kvs["url"] == nil || kvs["url"].Value != "https://a.b" {
+		t.Fatalf("alpha.sats not updated by spending the name")
+	}
+	if reg := mgr.ns.GetNameRegisterInfo("alpha.sats"); reg.Location != fmt.Sprintf("%064x:0", 12) {
+		t.Fatalf("alpha.sats at %s after transfer", reg.Location)
+	}
+}
+
+// 查询和注册使用同样的规则
+func TestCheckNameAvailabilityBitmap(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	for _, name := range []string{"123.bitmap", "abc.bitmap"} {
+		result := mgr.CheckNameAvailability(name)
+		if result.Available || result.Reason != common.NAME_NAMESPACE {
+			t.Fatalf("%s available %v, reason %s", name, result.Available, result.Reason)
+		}
+		if registerTestName(mgr, name, 10) {
+			t.Fatalf("%s registered as sns name", name)
+		}
+	}
+
+	if result := mgr.CheckNameAvailability("alpha.sats"); !result.Available {
+		t.Fatalf("alpha.sats not available, reason %s", result.Reason)
+	}
+	registerTestName(mgr, "alpha.sats", 10)
+	result := mgr.CheckNameAvailability("ALPHA.sats")
+	if result.Available || result.Reason != common.NAME_TAKEN || result.RegisteredBy != fmt.Sprintf("%064xi0", 10) {
+		t.Fatalf("alpha.sats available %v, reason %s, registered by %s",
+			result.Available, result.Reason, result.RegisteredBy)
+	}
+}
+
+// 输入中带有一个铭文的交易，delegate不为nil时没有内容，只有delegate字段
+func newInscriptionTx(seed byte, content string, delegate *chainhash.Hash) *wire.MsgTx {
+	builder := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_FALSE).
+		AddOp(txscript.OP_IF).
+		AddData([]byte("ord"))
+	if delegate != nil {
+		builder.AddOp(txscript.OP_DATA_1).AddOp(common.FIELD_DELEGATE).AddData(delegate[:])
+	} else {
+		builder.AddOp(txscript.OP_DATA_1).AddOp(common.FIELD_CONTENT_TYPE).AddData([]byte("text/plain")).
+			AddOp(txscript.OP_0).AddData([]byte(content))
+	}
+	script, _ := builder.AddOp(txscript.OP_ENDIF).Script()
+
+	tx := wire.NewMsgTx(2)
+	input := wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{seed}}, nil, nil)
+	input.Witness = wire.TxWitness{make([]byte, 64), script, append([]byte{0xc1}, make([]byte, 32)...)}
+	tx.AddTxIn(input)
+	tx.AddTxOut(wire.NewTxOut(10000, []byte{txscript.OP_1}))
+	return tx
+}
+
+// 每个区块一个交易，从高度0开始
+func newTestChain(t *testing.T, txs ...*wire.MsgTx) *base_indexer.FixtureBlockSource {
+	source := base_indexer.NewFixtureBlockSource()
+	var prev chainhash.Hash
+	for i, tx := range txs {
+		block := wire.NewMsgBlock(wire.NewBlockHeader(1, &prev, &chainhash.Hash{}, 0, uint32(i)))
+		block.Header.Timestamp = time.Unix(int64(1700000000+i), 0)
+		block.AddTransaction(tx)
+		var buf bytes.Buffer
+		err := block.Serialize(&buf)
+		if err == nil {
+			_, err = source.AddBlock(buf.Bytes())
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev = block.BlockHash()
+	}
+	return source
+}
+
+// 只保存被delegate引用的铭文内容，其他内容需要时从区块读取
+func TestDelegateContent(t *testing.T) {
+	target := newInscriptionTx(1, "alpha.sats", nil)
+	targetHash := target.TxHash()
+	other := newInscriptionTx(2, "x", nil)
+	delegator := newInscriptionTx(3, "", &targetHash)
+	source := newTestChain(t, other, target, delegator)
+
+	// 区块1的名字太短，区块2可以注册
+	policy := common.NewNameLenPolicy()
+	policy.AddRule(&common.NameLenRule{Namespace: "sats", Unit: common.NAME_LEN_BYTE, Min: 20})
+	policy.AddRule(&common.NameLenRule{Namespace: "sats", Unit: common.NAME_LEN_BYTE, Min: 1, ActivationHeight: 2})
+	mgr := newTestIndexerMgr(t, source)
+	mgr.WithNameLenPolicy(policy)
+	mgr.syncToChainTip(make(chan struct{}))
+
+	// 协议使用delegate的内容
+	checkNameHeight(t, mgr, "alpha.sats", 2)
+	delegatorId := delegator.TxHash().String() + "i0"
+	if reg := mgr.ns.GetNameRegisterInfo("alpha.sats"); reg.Nft.Base.InscriptionId != delegatorId {
+		t.Fatalf("alpha.sats registered by %s, expected %s", reg.Nft.Base.InscriptionId, delegatorId)
+	}
+
+	targetId := targetHash.String() + "i0"
+	if content := mgr.ns.GetContent(targetId); content == nil || string(content.Body) != "alpha.sats" {
+		t.Fatalf("content of delegate %s not saved", targetId)
+	}
+	otherId := other.TxHash().String() + "i0"
+	if mgr.ns.GetContent(otherId) != nil || mgr.ns.GetContent(delegatorId) != nil {
+		t.Fatalf("content of inscriptions not delegated to is saved")
+	}
+
+	// 查询时从区块读取
+	content := mgr.GetInscriptionContent(otherId)
+	if content == nil || string(content.Body) != "x" || string(content.ContentType) != "text/plain" {
+		t.Fatalf("content of %s not read from block", otherId)
+	}
+	content = mgr.GetInscriptionContent(delegatorId)
+	if content == nil || content.InscriptionId != targetId || string(content.Body) != "alpha.sats" {
+		t.Fatalf("content of %s not resolved to delegate", delegatorId)
+	}
+	if mgr.GetInscriptionContent(targetHash.String()+"i1") != nil {
+		t.Fatalf("content of inscription not exist")
+	}
+}
+
+// failures大于0时读取区块失败
+type flakyBlockSource struct {
+	*base_indexer.FixtureBlockSource
+	failures int
+	reads    int
+}
+
+func (s *flakyBlockSource) GetRawBlock(blockHash string) ([]byte, error) {
+	s.reads++
+	if s.failures > 0 {
+		s.failures--
+		return nil, fmt.Errorf("block %s not available", blockHash)
+	}
+	return s.FixtureBlockSource.GetRawBlock(blockHash)
+}
+
+// 读取delegate的内容失败时重试，一直失败时不能继续索引
+func TestDelegateContentRetry(t *testing.T) {
+	delay := delegateRetryDelay
+	delegateRetryDelay = time.Millisecond
+	t.Cleanup(func() { delegateRetryDelay = delay })
+
+	target := newInscriptionTx(1, "alpha.sats", nil)
+	source := &flakyBlockSource{FixtureBlockSource: newTestChain(t, target)}
+	mgr := newTestIndexerMgr(t, source)
+	mgr.syncToChainTip(make(chan struct{}))
+	targetId := target.TxHash().String() + "i0"
+
+	source.failures, source.reads = DELEGATE_READ_RETRIES-1, 0
+	if content := mgr.getDelegateContent(targetId); content == nil || string(content.Body) != "alpha.sats" {
+		t.Fatalf("delegate content not read after retries")
+	}
+	if source.reads != DELEGATE_READ_RETRIES {
+		t.Fatalf("%d reads, expected %d", source.reads, DELEGATE_READ_RETRIES)
+	}
+
+	// 已经保存的内容不再读取区块
+	source.failures, source.reads = DELEGATE_READ_RETRIES, 0
+	if mgr.getDelegateContent(targetId) == nil || source.reads != 0 {
+		t.Fatalf("saved delegate content read again")
+	}
+
+	other := newInscriptionTx(2, "beta.sats", nil)
+	source = &flakyBlockSource{FixtureBlockSource: newTestChain(t, other)}
+	mgr = newTestIndexerMgr(t, source)
+	mgr.syncToChainTip(make(chan struct{}))
+	source.failures, source.reads = DELEGATE_READ_RETRIES, 0
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic after %d failed reads", DELEGATE_READ_RETRIES)
+		}
+		if source.reads != DELEGATE_READ_RETRIES {
+			t.Fatalf("%d reads, expected %d", source.reads, DELEGATE_READ_RETRIES)
+		}
+	}()
+	mgr.getDelegateContent(other.TxHash().String() + "i0")
+}
+
+func gzipContent(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(content))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newEncodedInscription(body []byte, encoding string) *common.Inscription {
+	insc := &common.Inscription{Body: body, ContentType: []byte("text/plain"), ContentEncoding: []byte(encoding)}
+	insc.Content, insc.DecodeError = common.DecodeContent(body, insc.ContentEncoding)
+	return insc
+}
+
+// 保存的是原始内容和它的编码，解码后的内容只用于识别协议
+func TestEncodedContent(t *testing.T) {
+	body := gzipContent(t, "alpha.sats")
+	insc := newEncodedInscription(body, common.CONTENT_ENCODING_GZIP)
+	if _, ok := insc.Fields()[common.FIELD_CONTENT_ENCODING]; ok || string(insc.Fields()[common.FIELD_CONTENT]) != "alpha.sats" {
+		t.Fatalf("fields for protocols should have decoded content without encoding")
+	}
+
+	nft := newNft(insc, fmt.Sprintf("%064x", 1), 0, &common.Block{Height: 1})
+	if !bytes.Equal(nft.Base.Content, body) || string(nft.Base.ContentEncoding) != common.CONTENT_ENCODING_GZIP {
+		t.Fatalf("nft content is not the raw body with its encoding")
+	}
+	content := newInscriptionContent(insc, nft.Base.InscriptionId)
+	if !bytes.Equal(content.Body, body) || string(content.DecodedContent()) != "alpha.sats" {
+		t.Fatalf("content body %x, decoded %s", content.Body, content.DecodedContent())
+	}
+
+	// 不能解码时保留原始内容
+	bad := newEncodedInscription([]byte("not gzip"), common.CONTENT_ENCODING_GZIP)
+	if bad.DecodeError == nil {
+		t.Fatalf("decode should fail")
+	}
+	nft = newNft(bad, fmt.Sprintf("%064x", 2), 0, &common.Block{Height: 2})
+	content = newInscriptionContent(bad, nft.Base.InscriptionId)
+	if string(nft.Base.Content) != "not gzip" || string(content.Body) != "not gzip" || content.DecodedContent() != nil {
+		t.Fatalf("raw body dropped when decoding failed")
+	}
+
+	// delegate的内容也只使用解码后的内容，不带编码
+	mgr := newTestIndexerMgr(t, nil)
+	target := newInscriptionContent(insc, fmt.Sprintf("%064xi0", 1))
+	mgr.ns.AddContent(target)
+	delegator := &common.Inscription{Delegate: append([]byte{1}, make([]byte, 31)...)}
+	fields := delegator.Fields()
+	if mgr.handleDelegate(delegator, fmt.Sprintf("%064xi0", 3), fields) != target.InscriptionId {
+		t.Fatalf("delegate not parsed")
+	}
+	if string(fields[common.FIELD_CONTENT]) != "alpha.sats" || fields[common.FIELD_CONTENT_ENCODING] != nil {
+		t.Fatalf("delegate fields content %x encoding %s", fields[common.FIELD_CONTENT], fields[common.FIELD_CONTENT_ENCODING])
+	}
+}
+
+// 每个skeleton只记录第一个注册的名字
+func TestConfusableSkeletonIndex(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	for i, name := range []string{"google.sats", "g00gle.sats", "goog1e.sats"} {
+		if !registerTestName(mgr, name, 10+i) {
+			t.Fatalf("%s should be registered in flag mode", name)
+		}
+	}
+	for _, name := range []string{"g00gle.sats", "goog1e.sats"} {
+		if reg := mgr.ns.GetNameRegisterInfo(name); reg.ConfusableWith != "google.sats" {
+			t.Fatalf("%s confusable with %s, expected google.sats", name, reg.ConfusableWith)
+		}
+	}
+
+	skeleton := mgr.confusables.Skeleton("google.sats")
+	if name := mgr.ns.GetNameBySkeleton(skeleton); name != "google.sats" {
+		t.Fatalf("skeleton indexed %s in buffer", name)
+	}
+	mgr.ns.UpdateDB()
+	if name := mgr.ns.GetNameBySkeleton(skeleton); name != "google.sats" {
+		t.Fatalf("skeleton indexed %s in db", name)
+	}
+	if registerTestName(mgr, "GOOG1E.sats", 20) {
+		t.Fatalf("registered name should still collide")
+	}
+}
+
+// reject从生效高度开始，之前和flag一样
+func TestConfusableRejectHeight(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	mgr.WithConfusables(common.CONFUSABLE_REJECT, 100, nil)
+	registerTestName(mgr, "google.sats", 10)
+
+	if !registerTestName(mgr, "g00gle.sats", 99) {
+		t.Fatalf("confusable name rejected before activation")
+	}
+	if reg := mgr.ns.GetNameRegisterInfo("g00gle.sats"); reg.ConfusableWith != "google.sats" {
+		t.Fatalf("confusable name not flagged before activation")
+	}
+
+	result := mgr.checkSnsNameRegister("goog1e.sats", 100)
+	if result.Reason != common.NAME_CONFUSABLE || result.ConfusableWith != "google.sats" {
+		t.Fatalf("reason %s, confusable with %s after activation", result.Reason, result.ConfusableWith)
+	}
+	if registerTestName(mgr, "goog1e.sats", 100) {
+		t.Fatalf("confusable name registered after activation")
+	}
+}
+
+// 花费prev并铸造铭文，parents是铭文id为 txid i0 的parent
+func newChildTx(prev wire.OutPoint, content string, parents ...chainhash.Hash) *wire.MsgTx {
+	builder := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_FALSE).
+		AddOp(txscript.OP_IF).
+		AddData([]byte("ord")).
+		AddOp(txscript.OP_DATA_1).AddOp(common.FIELD_CONTENT_TYPE).AddData([]byte("text/plain"))
+	for _, parent := range parents {
+		builder.AddOp(txscript.OP_DATA_1).AddOp(common.FIELD_PARENT).AddData(parent[:])
+	}
+	script, _ := builder.AddOp(txscript.OP_0).AddData([]byte(content)).AddOp(txscript.OP_ENDIF).Script()
+
+	tx := wire.NewMsgTx(2)
+	input := wire.NewTxIn(&prev, nil, nil)
+	input.Witness = wire.TxWitness{make([]byte, 64), script, append([]byte{0xc1}, make([]byte, 32)...)}
+	tx.AddTxIn(input)
+	tx.AddTxOut(wire.NewTxOut(10000, []byte{txscript.OP_1}))
+	return tx
+}
+
+// parent需要在同一个交易中被花费，转移后的parent和child可以继续作为parent
+func TestParentValidation(t *testing.T) {
+	parent := newInscriptionTx(1, "parent", nil)
+	parentHash := parent.TxHash()
+	child := newChildTx(wire.OutPoint{Hash: parentHash}, "child", parentHash)
+	childHash := child.TxHash()
+	// 没有花费parent
+	orphan := newChildTx(wire.OutPoint{Hash: chainhash.Hash{2}}, "orphan", parentHash)
+	grandchild := newChildTx(wire.OutPoint{Hash: childHash}, "grandchild", childHash, parentHash)
+	source := newTestChain(t, parent, child, orphan, grandchild)
+
+	mgr := newTestIndexerMgr(t, source)
+	mgr.syncToChainTip(make(chan struct{}))
+
+	parentId := parentHash.String() + "i0"
+	childId := childHash.String() + "i0"
+	orphanId := orphan.TxHash().String() + "i0"
+	grandchildId := grandchild.TxHash().String() + "i0"
+
+	check := func(name string, got []string, expected ...string) {
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Fatalf("%s %v, expected %v", name, got, expected)
+		}
+	}
+	check("parent children", mgr.GetChildren(parentId), childId, grandchildId)
+	check("child children", mgr.GetChildren(childId), grandchildId)
+	check("child parents", mgr.GetParents(childId), parentId)
+	check("orphan parents", mgr.GetParents(orphanId))
+	check("grandchild parents", mgr.GetParents(grandchildId), childId, parentId)
+	// 新的铭文在前，然后是输入中转移过来的铭文
+	check("locations", mgr.GetInscriptionsAt(grandchild.TxHash().String()+":0"), grandchildId, childId, parentId)
+	check("spent location", mgr.GetInscriptionsAt(childHash.String()+":0"))
+}
+
+// jubilee之前被诅咒的铭文使用负数编号，之后使用正数编号并标记为vindicated
+func TestNumberInscriptionJubilee(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	mgr.jubileeHeight = 100
+
+	cases := []struct {
+		name        string
+		inscription *common.Inscription
+		height      int
+		onFirstSat  bool
+		id          int64
+		curse       string
+		vindicated  bool
+	}{
+		{"blessed", &common.Inscription{}, 99, false, 0, common.CURSE_NONE, false},
+		{"cursed", &common.Inscription{Pushnum: true}, 99, false, -1, common.CURSE_PUSHNUM, false},
+		{"not on first sat", &common.Inscription{}, 99, false, 1, common.CURSE_NONE, false},
+		// 同一个交易第一个聪上的铭文
+		{"first on sat", &common.Inscription{}, 99, true, 2, common.CURSE_NONE, false},
+		{"reinscription", &common.Inscription{}, 99, true, -2, common.CURSE_REINSCRIPTION, false},
+		{"cursed after jubilee", &common.Inscription{Pushnum: true}, 100, false, 3, common.CURSE_PUSHNUM, true},
+		{"reinscription after jubilee", &common.Inscription{}, 100, true, 4, common.CURSE_REINSCRIPTION, true},
+	}
+	var sat *common.SatInscriptions
+	for _, c := range cases {
+		nft, _ := testTxContext(c.height, testOwner)
+		sat = mgr.numberInscription(c.inscription, 0, c.onFirstSat, nft, sat)
+		if nft.Base.Id != c.id || nft.Base.Curse != c.curse || nft.Base.Vindicated != c.vindicated {
+			t.Fatalf("%s: id %d curse %q vindicated %v, expected %d %q %v",
+				c.name, nft.Base.Id, nft.Base.Curse, nft.Base.Vindicated, c.id, c.curse, c.vindicated)
+		}
+	}
+	if sat == nil || sat.Count != 3 || sat.FirstCursedOrVindicated {
+		t.Fatalf("sat %+v, expected 3 inscriptions with a blessed first one", sat)
+	}
+
+	// 第一个铭文在jubilee之后本应被诅咒时，第二个铭文不算重复铭刻
+	nft, _ := testTxContext(100, testOwner)
+	sat = mgr.numberInscription(&common.Inscription{Pushnum: true}, 0, true, nft, nil)
+	if !sat.FirstCursedOrVindicated {
+		t.Fatalf("vindicated first inscription not recorded")
+	}
+	nft, _ = testTxContext(100, testOwner)
+	mgr.numberInscription(&common.Inscription{}, 0, true, nft, sat)
+	if nft.Base.Curse != common.CURSE_NONE || nft.Base.Id != 6 {
+		t.Fatalf("second inscription curse %q id %d", nft.Base.Curse, nft.Base.Id)
+	}
+}
+
+// 限制的名字和同一高度注册的名字使用同样的规范化规则
+func TestNameRestrictionNormalizationHeight(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	mgr.WithNameNormalization(common.NORM_NFKC, 100)
+	err := mgr.WithNameRestrictions([]*common.NameRestriction{
+		{Name: "ＢＲＡＮＤ.sats", Type: common.NAME_BLOCKED, Reason: "test", ActivationHeight: 10},
+		{Name: "shop.sats", Type: common.NAME_RESERVED, Reason: "protocol"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		height int
+		reason string
+	}{
+		{"ｂｒａｎｄ.sats", 9, ""},
+		// 规范化生效之前只转小写
+		{"ｂｒａｎｄ.sats", 10, common.NAME_BLOCKED},
+		{"brand.sats", 10, ""},
+		{"ｂｒａｎｄ.sats", 100, common.NAME_BLOCKED},
+		{"brand.sats", 100, common.NAME_BLOCKED},
+		{"SHOP.sats", 0, common.NAME_RESERVED},
+		{"ｓｈｏｐ.sats", 100, common.NAME_RESERVED},
+	}
+	for _, c := range cases {
+		result := mgr.checkSnsNameRegister(c.name, c.height)
+		if result.Reason != c.reason {
+			t.Errorf("%s at %d: %q, expected %q", c.name, c.height, result.Reason, c.reason)
+			continue
+		}
+		if c.reason != "" && (result.Restriction == nil || result.Restriction.Type != c.reason) {
+			t.Errorf("%s at %d restriction %+v", c.name, c.height, result.Restriction)
+		}
+	}
+
+	// 已经注册的名字在之后被限制，查询时显示限制的原因
+	if !registerTestName(mgr, "brand.sats", 20) {
+		t.Fatalf("brand.sats should be registered before normalization")
+	}
+	if info := mgr.GetNameInfo("brand.sats"); info == nil || info.Restriction != nil {
+		t.Fatalf("brand.sats info %+v", info)
+	}
+	mgr.WithNameRestrictions([]*common.NameRestriction{{Name: "brand.sats", Type: common.NAME_RESERVED, Reason: "later"}})
+	if info := mgr.GetNameInfo("brand.sats"); info == nil || info.Restriction == nil || info.Restriction.Reason != "later" {
+		t.Fatalf("brand.sats info %+v", info)
+	}
+}
+
+func TestGetInscriptionOffset(t *testing.T) {
+	// 输出的总金额是1000
+	tx := &common.Transaction{Outputs: []*common.Output{{N: 0, Value: 600}, {N: 1, Value: 400}}}
+	cases := []struct {
+		name       string
+		pointer    []byte
+		inputIndex int
+		offset     uint64
+		exact      bool
+	}{
+		{"no pointer", nil, 0, 0, true},
+		{"no pointer in other input", nil, 1, 0, false},
+		{"inside first output", []byte{0x64}, 0, 100, true},
+		{"inside second output", []byte{0xe8, 0x02}, 1, 744, true},
+		{"last sat", []byte{0xe7, 0x03}, 0, 999, true},
+		// 超出输出的总金额时使用默认位置
+		{"beyond outputs", []byte{0xe8, 0x03}, 0, 0, true},
+		{"beyond outputs in other input", []byte{0xe8, 0x03}, 1, 0, false},
+		// 超过8字节的部分为0时有效，否则使用默认位置
+		{"long pointer", []byte{0x64, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 0, 100, true},
+		{"long pointer with high bytes", []byte{0x64, 0, 0, 0, 0, 0, 0, 0, 0, 1}, 0, 0, true},
+		{"max uint64", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0, 0, true},
+	}
+	for _, c := range cases {
+		insc := &common.Inscription{Pointer: c.pointer}
+		offset, exact := getInscriptionOffset(insc, c.inputIndex, tx)
+		if offset != c.offset || exact != c.exact {
+			t.Errorf("%s: %d %v, expected %d %v", c.name, offset, exact, c.offset, c.exact)
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 
 	"github.com/OLProtocol/ordx/common"
 	base_indexer "github.com/OLProtocol/ordx/indexer/base"
+	"github.com/OLProtocol/ordx/indexer/mempool"
 	"github.com/OLProtocol/ordx/indexer/ns"
+	"github.com/OLProtocol/ordx/indexer/protocol"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/dgraph-io/badger/v4"
 )
@@ -18,8 +20,28 @@ type IndexerMgr struct {
 	chaincfgParam   *chaincfg.Params
 	ordxFirstHeight int
 	ordFirstHeight  int
+	jubileeHeight   int // 之后被诅咒的铭文也使用正数编号
+	// 为0时使用默认值，reorg重新初始化后依然有效
+	periodFlushToDB int
+	fetchWorkers    int
+	blockPrefetch   int
+	blockSource     base_indexer.BlockSource
+	startHeight     int
 
 	ns *ns.NameService
+	// 名字的规范化和形似名字的处理规则
+	nameNormForm     string
+	nameNormHeight   int // 之前注册的名字只转小写
+	confusableMode   string
+	confusableHeight int // reject从这个高度开始生效，之前和flag一样
+	confusables      *common.ConfusableTable
+	nameLenPolicy    *common.NameLenPolicy
+	restrictions     *common.NameRestrictions
+	oldRestrictions  *common.NameRestrictions // 规范化生效之前使用，见getRestriction
+	// 元协议的处理器
+	protocols *protocol.Registry
+	// 内存池中待确认的名字注册，为nil时不启用
+	mempool *mempool.MempoolWatcher
 
 	mutex sync.RWMutex
 	// 跑数据
@@ -36,8 +58,14 @@ type IndexerMgr struct {
 	// 本地缓存，在区块更新时清空
 	addressToNftMap  map[string][]*common.Nft
 	addressToNameMap map[string][]*common.Nft
+
+	// 新区块通知，比如zmq，为nil时只依赖定时器
+	blockNotify <-chan string
 }
 
+// 没有新区块通知时的同步间隔
+const SYNC_INTERVAL = 10 * time.Second
+
 var instance *IndexerMgr
 
 func NewIndexerMgr(
@@ -56,6 +84,13 @@ func NewIndexerMgr(
 		compilingBackupDB: nil,
 		nsBackupDB:        nil,
 		rpcService:        nil,
+		nameNormForm:      common.NORM_NONE,
+		confusableMode:    common.CONFUSABLE_FLAG,
+		confusables:       common.NewConfusableTable(),
+		nameLenPolicy:     common.NewNameLenPolicy(),
+		restrictions:      common.NewNameRestrictions(),
+		oldRestrictions:   common.NewNameRestrictions(),
+		protocols:         protocol.NewDefaultRegistry(),
 	}
 
 	instance = mgr
@@ -63,12 +98,17 @@ func NewIndexerMgr(
 	case "mainnet":
 		instance.ordFirstHeight = 767430
 		instance.ordxFirstHeight = 827307
+		instance.jubileeHeight = 824544
+		instance.protocols.Register(&protocol.Brc20Handler{
+			SelfMintHeight: protocol.BRC20_SELF_MINT_HEIGHT_MAINNET}, 0)
 	case "testnet3":
 		instance.ordFirstHeight = 2413343
 		instance.ordxFirstHeight = 2570589
+		instance.jubileeHeight = 2544192
 	default: // testnet4
 		instance.ordFirstHeight = 0
 		instance.ordxFirstHeight = 0
+		instance.jubileeHeight = 0
 	}
 
 	return instance
@@ -80,6 +120,19 @@ func (b *IndexerMgr) Init() {
 		common.Log.Panicf("initDB failed. %v", err)
 	}
 	b.compiling = base_indexer.NewBaseIndexer(b.nsDB, b.chaincfgParam)
+	if b.periodFlushToDB > 0 {
+		b.compiling.WithPeriodFlushToDB(b.periodFlushToDB)
+	}
+	if b.fetchWorkers > 0 {
+		b.compiling.WithFetchWorkers(b.fetchWorkers)
+	}
+	if b.blockPrefetch > 0 {
+		b.compiling.WithBlockPrefetch(b.blockPrefetch)
+	}
+	if b.blockSource != nil {
+		b.compiling.WithBlockSource(b.blockSource)
+	}
+	b.compiling.WithStartHeight(b.startHeight)
 	b.compiling.Init(b.processOrdProtocol, b.forceUpdateDB)
 	b.lastCheckHeight = b.compiling.GetSyncHeight()
 
@@ -94,75 +147,190 @@ func (b *IndexerMgr) Init() {
 }
 
 func (b *IndexerMgr) WithPeriodFlushToDB(value int) *IndexerMgr {
+	b.periodFlushToDB = value
 	b.compiling.WithPeriodFlushToDB(value)
 	return b
 }
 
+func (b *IndexerMgr) WithFetchWorkers(value int) *IndexerMgr {
+	b.fetchWorkers = value
+	b.compiling.WithFetchWorkers(value)
+	return b
+}
+
+func (b *IndexerMgr) WithBlockPrefetch(value int) *IndexerMgr {
+	b.blockPrefetch = value
+	b.compiling.WithBlockPrefetch(value)
+	return b
+}
+
+func (b *IndexerMgr) WithBlockSource(source base_indexer.BlockSource) *IndexerMgr {
+	b.blockSource = source
+	b.compiling.WithBlockSource(source)
+	return b
+}
+
+// 名字长度规则，按生效高度判断，修改已经生效的规则需要重建索引
+func (b *IndexerMgr) WithNameLenPolicy(policy *common.NameLenPolicy) *IndexerMgr {
+	b.nameLenPolicy = policy
+	return b
+}
+
+// 保留和屏蔽的名字，需要在WithNameNormalization之后调用。
+// 名字分别按规范化生效前后的规则规范化，和同一高度注册的名字一致
+func (b *IndexerMgr) WithNameRestrictions(list []*common.NameRestriction) error {
+	restrictions := common.NewNameRestrictions()
+	oldRestrictions := common.NewNameRestrictions()
+	for _, item := range list {
+		r := *item
+		r.Name = b.normalizeNameAtHeight(item.Name, b.nameNormHeight)
+		err := restrictions.Add(&r)
+		if err != nil {
+			return err
+		}
+		old := *item
+		old.Name = b.normalizeNameAtHeight(item.Name, b.nameNormHeight-1)
+		err = oldRestrictions.Add(&old)
+		if err != nil {
+			return err
+		}
+	}
+	b.restrictions = restrictions
+	b.oldRestrictions = oldRestrictions
+	return nil
+}
+
+// 元协议的处理器，可以注册新的协议或者修改内置协议的生效高度
+func (b *IndexerMgr) GetProtocolRegistry() *protocol.Registry {
+	return b.protocols
+}
+
+// 启用内存池监控
+func (b *IndexerMgr) WithMempool(source mempool.MempoolSource, pollInterval time.Duration) *IndexerMgr {
+	b.mempool = mempool.NewMempoolWatcher(source, b.parseNameToRegister).WithPollInterval(pollInterval)
+	return b
+}
+
+// common.NORM_*，从height开始生效，之前注册的名字保持原来的结果。
+// 改变已有数据库的规范化方式或者生效高度需要重建索引
+func (b *IndexerMgr) WithNameNormalization(form string, height int) *IndexerMgr {
+	b.nameNormForm = form
+	b.nameNormHeight = height
+	return b
+}
+
+// common.CONFUSABLE_*，reject从height开始生效，之前注册的名字保持原来的结果。
+// table为nil时使用内置的形似字符表
+func (b *IndexerMgr) WithConfusables(mode string, height int, table *common.ConfusableTable) *IndexerMgr {
+	b.confusableMode = mode
+	b.confusableHeight = height
+	if table != nil {
+		b.confusables = table
+	}
+	return b
+}
+
+// 只对空数据库有效，要在Init之前调用
+func (b *IndexerMgr) WithStartHeight(height int) *IndexerMgr {
+	b.startHeight = height
+	return b
+}
+
+// 收到新区块通知时立即同步，定时器作为兜底
+func (b *IndexerMgr) WithBlockNotify(notify <-chan string) *IndexerMgr {
+	b.blockNotify = notify
+	return b
+}
+
 func (b *IndexerMgr) StartDaemon(stopChan chan bool) {
-	n := 10
-	ticker := time.NewTicker(time.Duration(n) * time.Second)
+	if b.mempool != nil {
+		b.mempool.Start()
+	}
+
+	runSyncLoop(b.syncToChainTip, SYNC_INTERVAL, b.blockNotify, stopChan)
+
+	if b.mempool != nil {
+		b.mempool.Stop()
+	}
+	b.closeDB()
+
+	common.Log.Info("IndexerMgr exited.")
+}
+
+// 同步到最新的区块，收到退出信号时返回false
+func (b *IndexerMgr) syncToChainTip(stopIndexerChan chan struct{}) bool {
+	ret := b.compiling.SyncToChainTip(stopIndexerChan)
+	if ret == 0 {
+		b.updateDB()
+	} else if ret > 0 {
+		// handle reorg
+		b.handleReorg(ret)
+	} else {
+		common.Log.Infof("IndexerMgr inner thread exit by SIGINT signal")
+		return false
+	}
+	return true
+}
+
+// 定时同步，收到新区块通知时立即同步。同一时间只有一个同步在运行，
+// 同步过程中收到通知时，在这次同步结束后再同步一次
+func runSyncLoop(sync func(stop chan struct{}) bool, interval time.Duration,
+	notify <-chan string, stopChan chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	stopIndexerChan := make(chan struct{}, 1) // 非阻塞
+	syncDoneChan := make(chan bool, 1)
 
-	bWantExit := false
 	isRunning := false
-	disableSync := false
+	bPendingNotify := false
 	tick := func() {
-		if disableSync {
+		if isRunning {
 			return
 		}
-		if !isRunning {
-			isRunning = true
-			go func() {
-				ret := b.compiling.SyncToChainTip(stopIndexerChan)
-				if ret == 0 {
-					b.updateDB()
-				} else if ret > 0 {
-					// handle reorg
-					b.handleReorg(ret)
-				} else {
-					common.Log.Infof("IndexerMgr inner thread exit by SIGINT signal")
-					bWantExit = true
-				}
-
-				isRunning = false
-			}()
-		}
+		isRunning = true
+		bPendingNotify = false
+		go func() {
+			syncDoneChan <- sync(stopIndexerChan)
+		}()
 	}
 
 	tick()
-	for !bWantExit {
+	for {
 		select {
 		case <-ticker.C:
-			if bWantExit {
-				break
-			}
 			tick()
+		case hash := <-notify:
+			common.Log.Debugf("IndexerMgr got new block %s", hash)
+			if isRunning {
+				// 当前同步结束后再同步一次
+				bPendingNotify = true
+			} else {
+				tick()
+			}
+		case ok := <-syncDoneChan:
+			isRunning = false
+			if !ok {
+				return
+			}
+			if bPendingNotify {
+				tick()
+			}
 		case <-stopChan:
 			common.Log.Info("IndexerMgr got SIGINT")
-			if bWantExit {
-				break
-			}
 			if isRunning {
 				select {
 				case stopIndexerChan <- struct{}{}:
 					// 成功发送
 				default:
-					// 通道已满或没有接收者，执行其他操作
-				}
-				for isRunning {
-					time.Sleep(time.Second / 10)
+					// 通道已满，同步已经在退出
 				}
+				<-syncDoneChan
 				common.Log.Info("IndexerMgr inner thread exited")
 			}
-			bWantExit = true
+			return
 		}
 	}
-
-	ticker.Stop()
-	b.closeDB()
-
-	common.Log.Info("IndexerMgr exited.")
 }
 
 func (b *IndexerMgr) closeDB() {
@@ -237,6 +405,13 @@ func (b *IndexerMgr) prepareDBBuffer() {
 	common.Log.Infof("backup instance %d cloned", b.compilingBackupDB.GetHeight())
 }
 
+// 下一个区块的高度，用于检查还没有确认的铭文
+func (b *IndexerMgr) nextHeight() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.rpcService.GetHeight() + 1
+}
+
 func (b *IndexerMgr) cleanDBBuffer() {
 	b.ns.Subtract(b.nsBackupDB)
 }
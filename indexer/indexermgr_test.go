@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+)
+
+// 记录每次同步，直到测试放行才结束
+type fakeSync struct {
+	started chan chan struct{}
+	release chan bool
+}
+
+func newFakeSync() *fakeSync {
+	return &fakeSync{started: make(chan chan struct{}, 8), release: make(chan bool)}
+}
+
+func (f *fakeSync) sync(stop chan struct{}) bool {
+	f.started <- stop
+	select {
+	case ok := <-f.release:
+		return ok
+	case <-stop:
+		return false
+	}
+}
+
+func (f *fakeSync) waitStarted(t *testing.T) chan struct{} {
+	t.Helper()
+	select {
+	case stop := <-f.started:
+		return stop
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync not started")
+		return nil
+	}
+}
+
+func (f *fakeSync) expectIdle(t *testing.T) {
+	t.Helper()
+	select {
+	case <-f.started:
+		t.Fatal("unexpected sync")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func startSyncLoop(f *fakeSync, notify chan string) (chan bool, chan struct{}) {
+	stopChan := make(chan bool)
+	exited := make(chan struct{})
+	go func() {
+		runSyncLoop(f.sync, time.Hour, notify, stopChan)
+		close(exited)
+	}()
+	return stopChan, exited
+}
+
+func waitExited(t *testing.T, exited chan struct{}) {
+	t.Helper()
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync loop not exited")
+	}
+}
+
+func TestSyncLoopNotifyDuringSync(t *testing.T) {
+	f := newFakeSync()
+	notify := make(chan string)
+	stopChan, exited := startSyncLoop(f, notify)
+
+	f.waitStarted(t)
+	// 同步过程中的多个通知只会再同步一次
+	notify <- "a"
+	notify <- "b"
+	f.expectIdle(t)
+	f.release <- true
+
+	f.waitStarted(t)
+	f.release <- true
+	f.expectIdle(t)
+
+	stopChan <- true
+	waitExited(t, exited)
+}
+
+func TestSyncLoopNotifyWhenIdle(t *testing.T) {
+	f := newFakeSync()
+	notify := make(chan string)
+	stopChan, exited := startSyncLoop(f, notify)
+
+	f.waitStarted(t)
+	f.release <- true
+	f.expectIdle(t)
+
+	notify <- "a"
+	f.waitStarted(t)
+
+	// 退出时通知正在运行的同步
+	stopChan <- true
+	waitExited(t, exited)
+}
+
+func TestSyncLoopExitWhenSyncStops(t *testing.T) {
+	f := newFakeSync()
+	_, exited := startSyncLoop(f, make(chan string))
+
+	f.waitStarted(t)
+	f.release <- false
+	waitExited(t, exited)
+}
@@ -0,0 +1,46 @@
+package indexer
+
+import (
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/ns"
+)
+
+// parent铭文的所有有效的child，按照铸造顺序。
+// 尽力而为的索引：区块中没有输入的金额，铭文按照输入i到输出i转移，
+// parent实际在其他输出时，后面的child不能通过检查，结果可能比ord少
+func (b *IndexerMgr) GetChildren(inscriptionId string) []string {
+	return b.ns.GetChildren(inscriptionId)
+}
+
+// 铭文的所有有效的parent，和GetChildren一样是尽力而为的结果
+func (b *IndexerMgr) GetParents(inscriptionId string) []string {
+	return b.ns.GetParents(inscriptionId)
+}
+
+// 输出 txid:vout 中的铭文，只有交易第一个聪上的铭文的位置一定准确，其他铭文见GetChildren
+func (b *IndexerMgr) GetInscriptionsAt(location string) []string {
+	return b.ns.GetInscriptionsAt(location)
+}
+
+// 铭文的内容，有delegate时返回delegate的内容，没有记录时返回nil
+func (b *IndexerMgr) GetInscriptionContent(inscriptionId string) *ns.InscriptionContent {
+	content := b.getContent(inscriptionId)
+	if content == nil || content.Delegate == "" {
+		return content
+	}
+	return b.resolveDelegate(inscriptionId, content.Delegate, b.getContent)
+}
+
+// 不修改索引的数据，读取失败时当做不存在
+func (b *IndexerMgr) getContent(inscriptionId string) *ns.InscriptionContent {
+	content := b.ns.GetContent(inscriptionId)
+	if content != nil {
+		return content
+	}
+	content, err := b.readInscriptionContent(inscriptionId)
+	if err != nil {
+		common.Log.Errorf("readInscriptionContent %s failed. %v", inscriptionId, err)
+		return nil
+	}
+	return content
+}
@@ -0,0 +1,132 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// 离线检查交易中的铭文，不访问数据库和网络，用于排查名字注册的问题
+type TxInspection struct {
+	Txid   string             `json:"txid"`
+	Height int                `json:"height"` // 使用的规则所在的高度
+	Inputs []*InputInspection `json:"inputs"`
+}
+
+type InputInspection struct {
+	Index     int                   `json:"index"`
+	Error     string                `json:"error,omitempty"` // witness格式错误时整个输入都没有铭文
+	Envelopes []*EnvelopeInspection `json:"envelopes"`
+}
+
+type EnvelopeInspection struct {
+	InscriptionId   string          `json:"inscriptionId"`
+	Offset          int             `json:"offset"` // 在输入中的序号
+	ContentType     string          `json:"contentType,omitempty"`
+	ContentEncoding string          `json:"contentEncoding,omitempty"`
+	Content         string          `json:"content,omitempty"`    // 解码后是utf8时
+	ContentHex      string          `json:"contentHex,omitempty"` // 解码后不是utf8时
+	DecodeError     string          `json:"decodeError,omitempty"`
+	Pointer         string          `json:"pointer,omitempty"`
+	Parents         []string        `json:"parents,omitempty"`
+	Delegate        string          `json:"delegate,omitempty"` // 索引时协议使用delegate的内容，这里不能解析
+	Metaprotocol    string          `json:"metaprotocol,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	MetadataError   string          `json:"metadataError,omitempty"`
+	// 没有考虑同一个聪上已有的铭文，所以不会是reinscription
+	Curse      string        `json:"curse,omitempty"`
+	Vindicated bool          `json:"vindicated,omitempty"` // jubilee之后被诅咒的铭文也使用正数编号
+	Protocol   string        `json:"protocol"`             // 纯文本时为空
+	Name       *NameDecision `json:"name,omitempty"`       // 不注册名字时为nil
+}
+
+type NameDecision struct {
+	Name string `json:"name"` // 规范化后的名字
+	// 只检查名字规则，是否已被注册和形似的名字依赖索引数据，离线时不能检查
+	Accepted    bool                    `json:"accepted"`
+	Reason      string                  `json:"reason,omitempty"` // common.NAME_*
+	Restriction *common.NameRestriction `json:"restriction,omitempty"`
+}
+
+// rawTx是序列化的交易，height小于等于0时使用最新的规则
+func (b *IndexerMgr) InspectTx(rawTx []byte, height int) (*TxInspection, error) {
+	var tx wire.MsgTx
+	err := tx.Deserialize(bytes.NewReader(rawTx))
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction: %v", err)
+	}
+	if height <= 0 {
+		height = math.MaxInt32
+	}
+
+	txid := tx.TxHash().String()
+	result := &TxInspection{Txid: txid, Height: height, Inputs: make([]*InputInspection, 0)}
+	id := 0
+	for i, input := range tx.TxIn {
+		inputResult := &InputInspection{Index: i, Envelopes: make([]*EnvelopeInspection, 0)}
+		result.Inputs = append(result.Inputs, inputResult)
+		inscriptions, err := common.ParseEnvelopes(input.Witness)
+		if err != nil {
+			inputResult.Error = err.Error()
+			continue
+		}
+		for _, insc := range inscriptions {
+			env := b.inspectInscription(insc, i, height)
+			env.InscriptionId = fmt.Sprintf("%si%d", txid, id)
+			inputResult.Envelopes = append(inputResult.Envelopes, env)
+			id++
+		}
+	}
+	return result, nil
+}
+
+func (b *IndexerMgr) inspectInscription(insc *common.Inscription, inputIndex int, height int) *EnvelopeInspection {
+	result := &EnvelopeInspection{
+		Offset:          insc.Offset,
+		ContentType:     string(insc.ContentType),
+		ContentEncoding: string(insc.ContentEncoding),
+		Parents:         insc.GetParents(),
+		Metaprotocol:    string(insc.Metaprotocol),
+		Curse:           common.GetCurse(insc, inputIndex, nil),
+	}
+	if utf8.Valid(insc.Content) {
+		result.Content = string(insc.Content)
+	} else {
+		result.ContentHex = hex.EncodeToString(insc.Content)
+	}
+	if insc.DecodeError != nil {
+		result.DecodeError = insc.DecodeError.Error()
+	}
+	if insc.Pointer != nil {
+		result.Pointer = hex.EncodeToString(insc.Pointer)
+	}
+	if insc.Delegate != nil {
+		result.Delegate, _ = insc.GetDelegate()
+	}
+	if insc.Metadata != nil {
+		metadata, err := common.Cbor2json(insc.Metadata)
+		if err != nil {
+			result.MetadataError = err.Error()
+		} else {
+			result.Metadata = metadata
+		}
+	}
+	result.Vindicated = result.Curse != common.CURSE_NONE && height >= b.jubileeHeight
+
+	env := newEnvelope(insc.Fields(), nil)
+	result.Protocol = env.Protocol
+	name, reason := b.checkNameAtHeight(env, height)
+	if name != "" {
+		result.Name = &NameDecision{Name: name, Accepted: reason == "", Reason: reason}
+		if reason != "" {
+			result.Name.Restriction = b.getRestriction(name, height)
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/OLProtocol/ordx/common"
+)
+
+// testdata/inspect_tx.hex: 第一个输入有alpha.sats，abc.bitmap和840000.bitmap三个铭文，
+// 第二个输入有被屏蔽的blocked.sats和太长的名字
+const (
+	inspectTxFile       = "testdata/inspect_tx.hex"
+	inspectExpectedFile = "testdata/inspect_tx.json"
+)
+
+// go test ./indexer -run TestInspectTx -update 重新生成inspect_tx.json
+func TestInspectTx(t *testing.T) {
+	mgr := newTestIndexerMgr(t, nil)
+	err := mgr.WithNameRestrictions([]*common.NameRestriction{
+		{Name: "blocked.sats", Type: common.NAME_BLOCKED, Reason: "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(inspectTxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := mgr.InspectTx(rawTx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output = append(output, '\n')
+
+	if *updateCorpus {
+		err = os.WriteFile(inspectExpectedFile, output, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(inspectExpectedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Fatalf("inspection changed, run with -update and check the diff\n%s", output)
+	}
+
+	// json中的名字决定
+	var decoded TxInspection
+	err = json.Unmarshal(expected, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		input, offset int
+		name          string
+		reason        string
+	}{
+		{0, 0, "alpha.sats", ""},
+		{0, 1, "abc.bitmap", common.NAME_NAMESPACE},
+		{0, 2, "840000.bitmap", ""},
+		{1, 0, "blocked.sats", common.NAME_BLOCKED},
+		{1, 1, "toolongnametoolongnametoolongname.sats", common.NAME_TOO_LONG},
+	}
+	for _, c := range cases {
+		decision := decoded.Inputs[c.input].Envelopes[c.offset].Name
+		if decision == nil || decision.Name != c.name || decision.Reason != c.reason || decision.Accepted != (c.reason == "") {
+			t.Errorf("input %d envelope %d: %+v, expected %s %q", c.input, c.offset, decision, c.name, c.reason)
+		}
+	}
+	if restriction := decoded.Inputs[1].Envelopes[0].Name.Restriction; restriction == nil || restriction.Reason != "test" {
+		t.Errorf("blocked name restriction %+v", restriction)
+	}
+}
@@ -0,0 +1,331 @@
+package mempool
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	DEFAULT_POLL_INTERVAL = 5 * time.Second
+	// 每次轮询最多拉取的新交易，避免启动时一次拉取整个内存池
+	MAX_FETCH_PER_POLL = 5000
+)
+
+// 返回铭文要注册的名字，不是名字注册时返回空
+type NameParser func(fields map[int][]byte) string
+
+// 内存池中还没有确认的名字注册
+type PendingRegister struct {
+	Name          string    `json:"name"`
+	InscriptionId string    `json:"inscriptionId"`
+	Txid          string    `json:"txid"`
+	FirstSeen     time.Time `json:"firstSeen"`
+	Fee           int64     `json:"fee"`     // satoshi，-1表示无法计算
+	VSize         int64     `json:"vsize"`   // vbyte
+	FeeRate       float64   `json:"feeRate"` // sat/vB，0表示无法计算
+}
+
+type pendingTx struct {
+	outpoints []wire.OutPoint
+	registers []*PendingRegister
+}
+
+// 轮询内存池，维护待确认的名字注册，交易被确认、替换或者从内存池中消失时移除
+type MempoolWatcher struct {
+	source    MempoolSource
+	parseName NameParser
+	interval  time.Duration
+
+	mutex sync.RWMutex
+	// 内存池中所有已处理的交易，避免重复拉取
+	txs   map[string]*pendingTx
+	spent map[wire.OutPoint]string
+	names map[string][]*PendingRegister // 按首次发现时间排序
+
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewMempoolWatcher(source MempoolSource, parseName NameParser) *MempoolWatcher {
+	return &MempoolWatcher{
+		source:    source,
+		parseName: parseName,
+		interval:  DEFAULT_POLL_INTERVAL,
+		txs:       make(map[string]*pendingTx),
+		spent:     make(map[wire.OutPoint]string),
+		names:     make(map[string][]*PendingRegister),
+		quit:      make(chan struct{}),
+	}
+}
+
+func (p *MempoolWatcher) WithPollInterval(interval time.Duration) *MempoolWatcher {
+	if interval > 0 {
+		p.interval = interval
+	}
+	return p
+}
+
+func (p *MempoolWatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			err := p.Poll()
+			if err != nil {
+				common.Log.Warnf("MempoolWatcher.Poll failed. %v", err)
+			}
+			select {
+			case <-p.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *MempoolWatcher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.quit)
+	})
+}
+
+// 同步一次内存池
+func (p *MempoolWatcher) Poll() error {
+	txids, err := p.source.GetRawMempool()
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(txids))
+	newTxids := make([]string, 0)
+	p.mutex.Lock()
+	for _, txid := range txids {
+		current[txid] = true
+		if _, ok := p.txs[txid]; !ok {
+			newTxids = append(newTxids, txid)
+		}
+	}
+	for txid := range p.txs {
+		if !current[txid] {
+			p.removeTx(txid)
+		}
+	}
+	p.mutex.Unlock()
+
+	if len(newTxids) > MAX_FETCH_PER_POLL {
+		newTxids = newTxids[:MAX_FETCH_PER_POLL]
+	}
+
+	now := time.Now()
+	for _, txid := range newTxids {
+		select {
+		case <-p.quit:
+			return nil
+		default:
+		}
+
+		tx, err := p.getTx(txid)
+		if err != nil {
+			// 可能刚好被确认或者被替换，下次轮询再处理
+			common.Log.Debugf("MempoolWatcher: get tx %s failed. %v", txid, err)
+			continue
+		}
+		p.addTx(txid, tx, now)
+	}
+
+	return nil
+}
+
+func (p *MempoolWatcher) getTx(txid string) (*wire.MsgTx, error) {
+	raw, err := p.source.GetRawTransaction(txid)
+	if err != nil {
+		return nil, err
+	}
+	var tx wire.MsgTx
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (p *MempoolWatcher) addTx(txid string, tx *wire.MsgTx, firstSeen time.Time) {
+	registers := make([]*PendingRegister, 0)
+	index := 0
+	for i, input := range tx.TxIn {
+		if len(input.Witness) == 0 {
+			continue
+		}
+		inscriptions, err := common.ParseInscription(input.Witness)
+		if err != nil {
+			common.Log.Debugf("mempool %s input %d: %v", txid, i, err)
+			continue
+		}
+		for _, fields := range inscriptions {
+			name := p.parseName(fields)
+			if name != "" {
+				registers = append(registers, &PendingRegister{
+					Name:          name,
+					InscriptionId: fmt.Sprintf("%si%d", txid, index),
+					Txid:          txid,
+					FirstSeen:     firstSeen,
+					Fee:           -1,
+				})
+			}
+			index++
+		}
+	}
+
+	if len(registers) > 0 {
+		fee, vsize := p.calcFee(tx)
+		for _, reg := range registers {
+			reg.Fee = fee
+			reg.VSize = vsize
+			if fee >= 0 && vsize > 0 {
+				reg.FeeRate = float64(fee) / float64(vsize)
+			}
+		}
+	}
+
+	outpoints := make([]wire.OutPoint, len(tx.TxIn))
+	for i, input := range tx.TxIn {
+		outpoints[i] = input.PreviousOutPoint
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.txs[txid]; ok {
+		return
+	}
+	// 花费同一个输出的旧交易已经被替换
+	for _, outpoint := range outpoints {
+		if old, ok := p.spent[outpoint]; ok && old != txid {
+			common.Log.Debugf("MempoolWatcher: tx %s replaced by %s", old, txid)
+			p.removeTx(old)
+		}
+		p.spent[outpoint] = txid
+	}
+	ptx := &pendingTx{outpoints: outpoints}
+	if len(registers) > 0 {
+		ptx.registers = registers
+		for _, reg := range registers {
+			p.names[reg.Name] = append(p.names[reg.Name], reg)
+			sort.SliceStable(p.names[reg.Name], func(i, j int) bool {
+				return p.names[reg.Name][i].FirstSeen.Before(p.names[reg.Name][j].FirstSeen)
+			})
+		}
+	}
+	p.txs[txid] = ptx
+}
+
+// 只有包含名字注册的交易才需要计算，需要拉取输入的前序交易
+func (p *MempoolWatcher) calcFee(tx *wire.MsgTx) (int64, int64) {
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	vsize := (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor
+
+	prevTxs := make(map[string]*wire.MsgTx)
+	inputValue := int64(0)
+	for _, input := range tx.TxIn {
+		prevTxid := input.PreviousOutPoint.Hash.String()
+		prevTx, ok := prevTxs[prevTxid]
+		if !ok {
+			var err error
+			prevTx, err = p.getTx(prevTxid)
+			if err != nil {
+				return -1, vsize
+			}
+			prevTxs[prevTxid] = prevTx
+		}
+		if int(input.PreviousOutPoint.Index) >= len(prevTx.TxOut) {
+			return -1, vsize
+		}
+		inputValue += prevTx.TxOut[input.PreviousOutPoint.Index].Value
+	}
+
+	outputValue := int64(0)
+	for _, output := range tx.TxOut {
+		outputValue += output.Value
+	}
+	return inputValue - outputValue, vsize
+}
+
+// 需要持有写锁
+func (p *MempoolWatcher) removeTx(txid string) {
+	ptx, ok := p.txs[txid]
+	if !ok {
+		return
+	}
+	for _, outpoint := range ptx.outpoints {
+		if p.spent[outpoint] == txid {
+			delete(p.spent, outpoint)
+		}
+	}
+	for _, reg := range ptx.registers {
+		regs := p.names[reg.Name]
+		for i, r := range regs {
+			if r == reg {
+				regs = append(regs[:i], regs[i+1:]...)
+				break
+			}
+		}
+		if len(regs) == 0 {
+			delete(p.names, reg.Name)
+		} else {
+			p.names[reg.Name] = regs
+		}
+	}
+	delete(p.txs, txid)
+}
+
+// 区块中的交易已经确认，同时移除与它们冲突的交易
+func (p *MempoolWatcher) RemoveConfirmed(block *common.Block) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, tx := range block.Transactions {
+		p.removeTx(tx.Txid)
+		for _, input := range tx.Inputs {
+			hash, err := chainhash.NewHashFromStr(input.Txid)
+			if err != nil {
+				continue
+			}
+			outpoint := wire.OutPoint{Hash: *hash, Index: uint32(input.Vout)}
+			if txid, ok := p.spent[outpoint]; ok {
+				common.Log.Debugf("MempoolWatcher: tx %s conflicts with confirmed tx %s", txid, tx.Txid)
+				p.removeTx(txid)
+			}
+		}
+	}
+}
+
+// 按首次发现时间排序
+func (p *MempoolWatcher) GetPendingRegisters(name string) []*PendingRegister {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	regs := p.names[name]
+	result := make([]*PendingRegister, len(regs))
+	for i, reg := range regs {
+		r := *reg
+		result[i] = &r
+	}
+	return result
+}
+
+func (p *MempoolWatcher) GetPendingNames() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	result := make([]string, 0, len(p.names))
+	for name := range p.names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,217 @@
+package mempool
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// 内存中的内存池，txs中没有的交易相当于节点没有开启txindex
+type fakeSource struct {
+	mempool []string
+	txs     map[string]*wire.MsgTx
+	fetched int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{txs: make(map[string]*wire.MsgTx)}
+}
+
+func (s *fakeSource) add(tx *wire.MsgTx) string {
+	txid := tx.TxHash().String()
+	s.txs[txid] = tx
+	s.mempool = append(s.mempool, txid)
+	return txid
+}
+
+func (s *fakeSource) remove(txid string) {
+	for i, id := range s.mempool {
+		if id == txid {
+			s.mempool = append(s.mempool[:i], s.mempool[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *fakeSource) GetRawMempool() ([]string, error) {
+	return append([]string{}, s.mempool...), nil
+}
+
+func (s *fakeSource) GetRawTransaction(txid string) ([]byte, error) {
+	s.fetched++
+	tx, ok := s.txs[txid]
+	if !ok {
+		return nil, fmt.Errorf("No such mempool or blockchain transaction")
+	}
+	var buf bytes.Buffer
+	err := tx.Serialize(&buf)
+	return buf.Bytes(), err
+}
+
+func parseTextName(fields map[int][]byte) string {
+	return string(fields[common.FIELD_CONTENT])
+}
+
+// 花费prev的交易，name不为空时输入中带有注册名字的文本铭文
+func newTx(prev wire.OutPoint, name string, value int64) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	input := wire.NewTxIn(&prev, nil, nil)
+	if name != "" {
+		script, _ := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_FALSE).
+			AddOp(txscript.OP_IF).
+			AddData([]byte("ord")).
+			AddOp(txscript.OP_DATA_1).AddOp(txscript.OP_DATA_1).
+			AddData([]byte("text/plain")).
+			AddOp(txscript.OP_0).
+			AddData([]byte(name)).
+			AddOp(txscript.OP_ENDIF).
+			Script()
+		controlBlock := append([]byte{0xc1}, make([]byte, 32)...)
+		input.Witness = wire.TxWitness{make([]byte, 64), script, controlBlock}
+	}
+	tx.AddTxIn(input)
+	tx.AddTxOut(wire.NewTxOut(value, []byte{txscript.OP_1}))
+	return tx
+}
+
+func outpoint(seed byte, index uint32) wire.OutPoint {
+	return wire.OutPoint{Hash: chainhash.Hash{seed}, Index: index}
+}
+
+func pendingTxids(p *MempoolWatcher, name string) []string {
+	result := make([]string, 0)
+	for _, reg := range p.GetPendingRegisters(name) {
+		result = append(result, reg.Txid)
+	}
+	return result
+}
+
+func TestMempoolRegister(t *testing.T) {
+	source := newFakeSource()
+	p := NewMempoolWatcher(source, parseTextName)
+	txid := source.add(newTx(outpoint(1, 0), "alpha.sats", 1000))
+	source.add(newTx(outpoint(2, 0), "", 1000))
+
+	err := p.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	regs := p.GetPendingRegisters("alpha.sats")
+	if len(regs) != 1 || regs[0].Txid != txid || regs[0].InscriptionId != txid+"i0" {
+		t.Fatalf("pending registers %v", pendingTxids(p, "alpha.sats"))
+	}
+	if names := p.GetPendingNames(); len(names) != 1 {
+		t.Fatalf("pending names %v, expected only alpha.sats", names)
+	}
+
+	// 从内存池消失
+	source.remove(txid)
+	p.Poll()
+	if len(p.GetPendingNames()) != 0 {
+		t.Fatalf("pending names %v after tx dropped", p.GetPendingNames())
+	}
+}
+
+func TestMempoolReplacement(t *testing.T) {
+	source := newFakeSource()
+	p := NewMempoolWatcher(source, parseTextName)
+	oldTxid := source.add(newTx(outpoint(1, 0), "alpha.sats", 1000))
+	p.Poll()
+
+	// 替换交易花费同一个输出，旧交易还在getrawmempool的结果里也要移除
+	newTxid := source.add(newTx(outpoint(1, 0), "beta.sats", 900))
+	p.Poll()
+	if txids := pendingTxids(p, "alpha.sats"); len(txids) != 0 {
+		t.Fatalf("replaced tx %s still pending: %v", oldTxid, txids)
+	}
+	if txids := pendingTxids(p, "beta.sats"); len(txids) != 1 || txids[0] != newTxid {
+		t.Fatalf("pending beta.sats %v, expected %s", txids, newTxid)
+	}
+	if p.spent[outpoint(1, 0)] != newTxid {
+		t.Fatalf("outpoint spent by %s, expected %s", p.spent[outpoint(1, 0)], newTxid)
+	}
+}
+
+func TestMempoolFetchCap(t *testing.T) {
+	source := newFakeSource()
+	p := NewMempoolWatcher(source, parseTextName)
+	total := MAX_FETCH_PER_POLL + 10
+	for i := 0; i < total; i++ {
+		source.add(newTx(wire.OutPoint{Hash: chainhash.Hash{1}, Index: uint32(i)}, "", 1000))
+	}
+
+	p.Poll()
+	if source.fetched != MAX_FETCH_PER_POLL || len(p.txs) != MAX_FETCH_PER_POLL {
+		t.Fatalf("fetched %d txs in first poll, expected %d", source.fetched, MAX_FETCH_PER_POLL)
+	}
+	// 剩下的交易在下次轮询拉取，已经处理过的不再拉取
+	p.Poll()
+	if source.fetched != total || len(p.txs) != total {
+		t.Fatalf("fetched %d txs after second poll, expected %d", source.fetched, total)
+	}
+}
+
+func TestMempoolRemoveConfirmed(t *testing.T) {
+	source := newFakeSource()
+	p := NewMempoolWatcher(source, parseTextName)
+	confirmed := source.add(newTx(outpoint(1, 0), "alpha.sats", 1000))
+	conflicted := source.add(newTx(outpoint(2, 0), "beta.sats", 1000))
+	pending := source.add(newTx(outpoint(3, 0), "gamma.sats", 1000))
+	p.Poll()
+
+	// 区块打包了confirmed，以及另一个花费outpoint(2, 0)的交易
+	block := &common.Block{
+		Height: 100,
+		Transactions: []*common.Transaction{
+			{Txid: confirmed, Inputs: []*common.Input{{Txid: chainhash.Hash{1}.String(), Vout: 0}}},
+			{Txid: "other", Inputs: []*common.Input{{Txid: chainhash.Hash{2}.String(), Vout: 0}}},
+		},
+	}
+	p.RemoveConfirmed(block)
+
+	if _, ok := p.txs[confirmed]; ok {
+		t.Fatalf("confirmed tx still pending")
+	}
+	if _, ok := p.txs[conflicted]; ok {
+		t.Fatalf("conflicted tx still pending")
+	}
+	names := p.GetPendingNames()
+	if len(names) != 1 || names[0] != "gamma.sats" || pendingTxids(p, "gamma.sats")[0] != pending {
+		t.Fatalf("pending names %v, expected only gamma.sats", names)
+	}
+	if len(p.spent) != 1 {
+		t.Fatalf("%d spent outpoints left, expected 1", len(p.spent))
+	}
+}
+
+func TestMempoolFee(t *testing.T) {
+	source := newFakeSource()
+	p := NewMempoolWatcher(source, parseTextName)
+
+	// 前序交易可以查询时计算手续费
+	prev := newTx(outpoint(8, 0), "", 5000)
+	source.txs[prev.TxHash().String()] = prev
+	source.add(newTx(wire.OutPoint{Hash: prev.TxHash(), Index: 0}, "alpha.sats", 4000))
+	// 节点没有txindex，前序交易查不到
+	source.add(newTx(outpoint(9, 0), "beta.sats", 4000))
+	p.Poll()
+
+	regs := p.GetPendingRegisters("alpha.sats")
+	if len(regs) != 1 || regs[0].Fee != 1000 || regs[0].VSize <= 0 {
+		t.Fatalf("alpha.sats fee %d vsize %d, expected fee 1000", regs[0].Fee, regs[0].VSize)
+	}
+	if regs[0].FeeRate != float64(1000)/float64(regs[0].VSize) {
+		t.Fatalf("alpha.sats fee rate %f", regs[0].FeeRate)
+	}
+
+	regs = p.GetPendingRegisters("beta.sats")
+	if len(regs) != 1 || regs[0].Fee != -1 || regs[0].FeeRate != 0 || regs[0].VSize <= 0 {
+		t.Fatalf("beta.sats fee %d rate %f vsize %d, expected unknown fee", regs[0].Fee, regs[0].FeeRate, regs[0].VSize)
+	}
+}
@@ -0,0 +1,33 @@
+package mempool
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/OLProtocol/ordx/share/bitcoin_rpc"
+)
+
+// 内存池数据来源
+type MempoolSource interface {
+	GetRawMempool() ([]string, error)
+	// 序列化后的交易数据
+	GetRawTransaction(txid string) ([]byte, error)
+}
+
+type RpcMempoolSource struct{}
+
+func (s *RpcMempoolSource) GetRawMempool() ([]string, error) {
+	return bitcoin_rpc.ShareBitconRpc.GetRawMempool()
+}
+
+func (s *RpcMempoolSource) GetRawTransaction(txid string) ([]byte, error) {
+	rawTx, err := bitcoin_rpc.ShareBitconRpc.GetRawTransaction(txid, false)
+	if err != nil {
+		return nil, err
+	}
+	txHex, ok := rawTx.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected getrawtransaction result %v", rawTx)
+	}
+	return hex.DecodeString(txHex)
+}
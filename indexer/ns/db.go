@@ -2,6 +2,7 @@ package ns
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/OLProtocol/ordx/common"
@@ -17,3 +18,126 @@ func loadNameFromDB(name string, value *NameValueInDB, txn *badger.Txn) error {
 func GetNameKey(name string) string {
 	return fmt.Sprintf("%s%s", DB_PREFIX_NAME, strings.ToLower(name))
 }
+
+func GetSkeletonKey(skeleton string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_SKELETON, skeleton)
+}
+
+func GetKeyValueKey(name, key string) string {
+	return fmt.Sprintf("%s%s-%s", DB_PREFIX_KV, name, key)
+}
+
+func GetPrimaryNameKey(address string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_PRIMARY, address)
+}
+
+func GetLocationKey(location string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_LOCATION, location)
+}
+
+func GetOwnerKey(address, name string) string {
+	return fmt.Sprintf("%s%s-%s", DB_PREFIX_OWNER, address, name)
+}
+
+// 地址持有的所有名字
+func loadNamesByOwnerFromDB(address string, txn *badger.Txn) ([]string, error) {
+	result := make([]string, 0)
+	prefix := []byte(GetOwnerKey(address, ""))
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		result = append(result, string(it.Item().Key()[len(prefix):]))
+	}
+	return result, nil
+}
+
+func GetTickerKey(ticker string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_TICKER, ticker)
+}
+
+func GetSatKey(location string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_SAT, location)
+}
+
+func GetInscriptionLocationKey(location string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_INSC_LOC, location)
+}
+
+func GetContentKey(inscriptionId string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_CONTENT, inscriptionId)
+}
+
+func GetRevealKey(txid string) string {
+	return fmt.Sprintf("%s%s", DB_PREFIX_REVEAL, txid)
+}
+
+func GetChildKey(parent, child string) string {
+	return fmt.Sprintf("%s%s-%s", DB_PREFIX_CHILD, parent, child)
+}
+
+func GetParentKey(child, parent string) string {
+	return fmt.Sprintf("%s%s-%s", DB_PREFIX_PARENT, child, parent)
+}
+
+// 前缀后面的铭文id，按照铸造顺序
+func loadRelationsFromDB(prefix string, txn *badger.Txn) ([]string, error) {
+	type relation struct {
+		id    string
+		order int64
+	}
+	relations := make([]*relation, 0)
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+		item := it.Item()
+		var order int64
+		err := item.Value(func(v []byte) error {
+			return common.DecodeBytes(v, &order)
+		})
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, &relation{id: string(item.Key()[len(prefix):]), order: order})
+	}
+	sort.SliceStable(relations, func(i, j int) bool {
+		return relations[i].order < relations[j].order
+	})
+
+	result := make([]string, 0, len(relations))
+	for _, r := range relations {
+		result = append(result, r.id)
+	}
+	return result, nil
+}
+
+func loadStringFromDB(key string, txn *badger.Txn) (string, error) {
+	var value string
+	err := common.GetValueFromDB([]byte(key), txn, &value)
+	return value, err
+}
+
+// 名字的所有属性
+func loadKeyValuesFromDB(name string, txn *badger.Txn) (map[string]*common.KeyValueInDB, error) {
+	result := make(map[string]*common.KeyValueInDB)
+	prefix := []byte(GetKeyValueKey(name, ""))
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		var value common.KeyValueInDB
+		err := item.Value(func(v []byte) error {
+			return common.DecodeBytes(v, &value)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result[string(item.Key()[len(prefix):])] = &value
+	}
+	return result, nil
+}
+
+func loadSkeletonFromDB(skeleton string, txn *badger.Txn) (string, error) {
+	return loadStringFromDB(GetSkeletonKey(skeleton), txn)
+}
@@ -0,0 +1,324 @@
+package ns
+
+import (
+	"github.com/OLProtocol/ordx/common"
+	"github.com/dgraph-io/badger/v4"
+)
+
+// 下一个铭文编号
+type InscriptionNumbers struct {
+	Blessed int64 // 从0开始递增
+	Cursed  int64 // 从-1开始递减
+}
+
+// 区块中没有聪的范围，只跟踪输出的第一个聪上的铭文
+type SatEvent struct {
+	OldLocation string // 为空表示之前没有记录
+	Location    string // 为空表示不再跟踪
+	Sat         *common.SatInscriptions
+}
+
+func (p *NameService) loadInscriptionNumbers() {
+	p.numbers = InscriptionNumbers{Blessed: 0, Cursed: -1}
+	err := p.db.View(func(txn *badger.Txn) error {
+		return common.GetValueFromDB([]byte(DB_KEY_INSCRIPTION_NUMBERS), txn, &p.numbers)
+	})
+	if err != nil && err != badger.ErrKeyNotFound {
+		common.Log.Panicf("NameService load inscription numbers failed. %v", err)
+	}
+}
+
+// 分配铭文编号，被诅咒的铭文使用负数
+func (p *NameService) NewInscriptionNumber(cursed bool) int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if cursed {
+		number := p.numbers.Cursed
+		p.numbers.Cursed--
+		return number
+	}
+	number := p.numbers.Blessed
+	p.numbers.Blessed++
+	return number
+}
+
+// 输出 txid:vout 的第一个聪上的铭文，没有时返回nil
+func (p *NameService) GetInscribedSat(location string) *common.SatInscriptions {
+	p.mutex.RLock()
+	sat, ok := p.sats[location]
+	p.mutex.RUnlock()
+	if ok {
+		return sat
+	}
+
+	var value common.SatInscriptions
+	err := p.db.View(func(txn *badger.Txn) error {
+		return common.GetValueFromDB([]byte(GetSatKey(location)), txn, &value)
+	})
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// 聪从oldLocation转移到location，两者都可以为空
+func (p *NameService) MoveInscribedSat(oldLocation, location string, sat *common.SatInscriptions) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	event := &SatEvent{OldLocation: oldLocation, Location: location, Sat: sat}
+	p.satEvents = append(p.satEvents, event)
+	p.applySatEvent(event)
+}
+
+func (p *NameService) applySatEvent(event *SatEvent) {
+	if event.OldLocation != "" {
+		p.sats[event.OldLocation] = nil
+	}
+	if event.Location != "" {
+		p.sats[event.Location] = event.Sat
+	}
+}
+
+func (p *NameService) updateInscriptionDB(wb *badger.WriteBatch) {
+	for _, event := range p.satEvents {
+		if event.OldLocation != "" {
+			key := GetSatKey(event.OldLocation)
+			err := wb.Delete([]byte(key))
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error deleting %s in db %v", key, err)
+			}
+		}
+		if event.Location != "" {
+			key := GetSatKey(event.Location)
+			err := common.SetDB([]byte(key), event.Sat, wb)
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+			}
+		}
+	}
+
+	err := common.SetDB([]byte(DB_KEY_INSCRIPTION_NUMBERS), &p.numbers, wb)
+	if err != nil {
+		common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", DB_KEY_INSCRIPTION_NUMBERS, err)
+	}
+}
+
+// 输出中的铭文转移，和名字一样使用近似的聪的位置
+type InscriptionMove struct {
+	OldLocation string // 为空表示之前没有记录
+	Location    string // 为空表示不再跟踪
+	Ids         []string
+}
+
+type InscriptionChild struct {
+	Parent string
+	Child  string
+	Order  int64 // 已经分配编号的铭文数量，用于按照铸造顺序排序
+}
+
+// 输出 txid:vout 中的铭文
+func (p *NameService) GetInscriptionsAt(location string) []string {
+	p.mutex.RLock()
+	ids, ok := p.inscLocations[location]
+	p.mutex.RUnlock()
+	if ok {
+		return ids
+	}
+
+	var value []string
+	err := p.db.View(func(txn *badger.Txn) error {
+		return common.GetValueFromDB([]byte(GetInscriptionLocationKey(location)), txn, &value)
+	})
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// 输出中的铭文从oldLocation转移到location，两者都可以为空
+func (p *NameService) MoveInscriptions(oldLocation, location string, ids []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	move := &InscriptionMove{OldLocation: oldLocation, Location: location, Ids: ids}
+	p.inscMoves = append(p.inscMoves, move)
+	p.applyInscriptionMove(move)
+}
+
+func (p *NameService) applyInscriptionMove(move *InscriptionMove) {
+	if move.OldLocation != "" {
+		p.inscLocations[move.OldLocation] = nil
+	}
+	if move.Location != "" {
+		p.inscLocations[move.Location] = move.Ids
+	}
+}
+
+// 每个有效的parent都调用
+func (p *NameService) AddChild(parent, child string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	order := p.numbers.Blessed - p.numbers.Cursed - 1
+	p.children = append(p.children, &InscriptionChild{Parent: parent, Child: child, Order: order})
+}
+
+// 按照铸造顺序
+func (p *NameService) GetChildren(parent string) []string {
+	result := p.loadRelationsFromDB(GetChildKey(parent, ""))
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for _, relation := range p.children {
+		if relation.Parent == parent {
+			result = append(result, relation.Child)
+		}
+	}
+	return result
+}
+
+func (p *NameService) GetParents(child string) []string {
+	result := p.loadRelationsFromDB(GetParentKey(child, ""))
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for _, relation := range p.children {
+		if relation.Child == child {
+			result = append(result, relation.Parent)
+		}
+	}
+	return result
+}
+
+func (p *NameService) loadRelationsFromDB(prefix string) []string {
+	var result []string
+	err := p.db.View(func(txn *badger.Txn) error {
+		var err error
+		result, err = loadRelationsFromDB(prefix, txn)
+		return err
+	})
+	if err != nil {
+		common.Log.Errorf("loadRelationsFromDB %s failed. %v", prefix, err)
+	}
+	return result
+}
+
+func (p *NameService) updateInscriptionRelationDB(wb *badger.WriteBatch) {
+	for _, move := range p.inscMoves {
+		if move.OldLocation != "" {
+			key := GetInscriptionLocationKey(move.OldLocation)
+			err := wb.Delete([]byte(key))
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error deleting %s in db %v", key, err)
+			}
+		}
+		if move.Location != "" {
+			key := GetInscriptionLocationKey(move.Location)
+			err := common.SetDB([]byte(key), move.Ids, wb)
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+			}
+		}
+	}
+
+	for _, relation := range p.children {
+		for _, key := range []string{GetChildKey(relation.Parent, relation.Child), GetParentKey(relation.Child, relation.Parent)} {
+			err := common.SetDB([]byte(key), relation.Order, wb)
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+			}
+		}
+	}
+}
+
+// 有铭文的交易所在的高度，用于按需读取铭文的内容
+type InscriptionReveal struct {
+	Txid   string
+	Height int
+}
+
+// 每个有铭文的交易都调用。铭文被引用之前不知道是否会作为delegate，所以每个交易都需要记录，
+// 每条记录大约100字节，主网有几千万个这样的交易，需要几GB的空间
+func (p *NameService) AddReveal(txid string, height int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.revealAdded = append(p.revealAdded, &InscriptionReveal{Txid: txid, Height: height})
+	p.reveals[txid] = height
+}
+
+// 交易中没有铭文时返回false
+func (p *NameService) GetRevealHeight(txid string) (int, bool) {
+	p.mutex.RLock()
+	height, ok := p.reveals[txid]
+	p.mutex.RUnlock()
+	if ok {
+		return height, true
+	}
+
+	err := p.db.View(func(txn *badger.Txn) error {
+		return common.GetValueFromDB([]byte(GetRevealKey(txid)), txn, &height)
+	})
+	if err != nil {
+		return 0, false
+	}
+	return height, true
+}
+
+// 铭文的内容，用于解析delegate，超过限制时不保存
+type InscriptionContent struct {
+	InscriptionId   string
+	ContentType     []byte
+	ContentEncoding []byte // Body的编码
+	Body            []byte // 链上的原始内容
+	Content         []byte // 有ContentEncoding时解码后的内容，不能解码时为nil
+	Truncated       bool   // Body和Content都没有保存
+	Delegate        string
+}
+
+// 协议使用的内容，没有ContentEncoding时就是Body
+func (p *InscriptionContent) DecodedContent() []byte {
+	if len(p.ContentEncoding) == 0 {
+		return p.Body
+	}
+	return p.Content
+}
+
+// 只保存被delegate引用的铭文，其他铭文的内容需要时从区块读取
+func (p *NameService) AddContent(content *InscriptionContent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.contentAdded = append(p.contentAdded, content)
+	p.contents[content.InscriptionId] = content
+}
+
+// 没有记录时返回nil
+func (p *NameService) GetContent(inscriptionId string) *InscriptionContent {
+	p.mutex.RLock()
+	content, ok := p.contents[inscriptionId]
+	p.mutex.RUnlock()
+	if ok {
+		return content
+	}
+
+	var value InscriptionContent
+	err := p.db.View(func(txn *badger.Txn) error {
+		return common.GetValueFromDB([]byte(GetContentKey(inscriptionId)), txn, &value)
+	})
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+func (p *NameService) updateInscriptionContentDB(wb *badger.WriteBatch) {
+	for _, reveal := range p.revealAdded {
+		key := GetRevealKey(reveal.Txid)
+		err := common.SetDB([]byte(key), reveal.Height, wb)
+		if err != nil {
+			common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+		}
+	}
+	for _, content := range p.contentAdded {
+		key := GetContentKey(content.InscriptionId)
+		err := common.SetDB([]byte(key), content, wb)
+		if err != nil {
+			common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package ns
 
 import (
+	"sort"
 	"strings"
 
+	"github.com/OLProtocol/ordx/common"
 	"github.com/dgraph-io/badger/v4"
 )
 
@@ -11,9 +13,9 @@ func (p *NameService) GetNameRegisterInfo(name string) *NameRegister {
 	reg := p.getNameInBuffer(name)
 	if reg != nil {
 		// nft 可能已经被转移了，更新属性
-
-		// reg.Nft
-		return reg
+		r := *reg
+		p.applyTransfer(&r)
+		return &r
 	}
 
 	value := NameValueInDB{}
@@ -30,11 +32,160 @@ func (p *NameService) GetNameRegisterInfo(name string) *NameRegister {
 	// 	return nil
 	// }
 
-	reg = &NameRegister{Nft: nil, Name: value.Name}
+	// 数据库中只保存了nft的部分信息
+	nft := &common.Nft{Base: &common.InscribeBaseContent{
+		InscriptionId: value.InscriptionId,
+		Id:            value.NftId,
+		Sat:           value.Sat,
+	}}
+	reg = &NameRegister{
+		Nft:            nft,
+		Name:           value.Name,
+		Original:       value.Original,
+		Skeleton:       value.Skeleton,
+		ConfusableWith: value.ConfusableWith,
+		Owner:          value.Owner,
+		Location:       value.Location,
+	}
+	p.applyTransfer(reg)
 
 	return reg
 }
 
+func (p *NameService) applyTransfer(reg *NameRegister) {
+	event := p.getTransferInBuffer(reg.Name)
+	if event != nil {
+		reg.Owner = event.Address
+		reg.Location = event.Location
+	}
+}
+
+// 名字的所有属性，包括还在缓存中的修改
+func (p *NameService) GetKeyValues(name string) map[string]*common.KeyValueInDB {
+	name = strings.ToLower(name)
+	var result map[string]*common.KeyValueInDB
+	err := p.db.View(func(txn *badger.Txn) error {
+		var err error
+		result, err = loadKeyValuesFromDB(name, txn)
+		return err
+	})
+	if err != nil {
+		common.Log.Errorf("loadKeyValuesFromDB %s failed. %v", name, err)
+		result = make(map[string]*common.KeyValueInDB)
+	}
+
+	for _, event := range p.getKeyValuesInBuffer(name) {
+		if event.Value == "" {
+			delete(result, event.Key)
+		} else {
+			result[event.Key] = &common.KeyValueInDB{Value: event.Value, InscriptionId: event.InscriptionId}
+		}
+	}
+	return result
+}
+
+// ticker需要是小写，没有部署时返回nil
+func (p *NameService) GetTicker(ticker string) *Brc20Ticker {
+	t := p.getTickerInBuffer(ticker)
+	if t != nil {
+		return t
+	}
+
+	var value Brc20Ticker
+	err := p.db.View(func(txn *badger.Txn) error {
+		return common.GetValueFromDB([]byte(GetTickerKey(ticker)), txn, &value)
+	})
+	if err != nil {
+		return nil
+	}
+	return &value
+}
+
+// 地址当前持有的名字，按名字排序
+func (p *NameService) GetNamesByOwner(address string) []string {
+	var candidates []string
+	err := p.db.View(func(txn *badger.Txn) error {
+		var err error
+		candidates, err = loadNamesByOwnerFromDB(address, txn)
+		return err
+	})
+	if err != nil {
+		common.Log.Errorf("loadNamesByOwnerFromDB %s failed. %v", address, err)
+	}
+	candidates = append(candidates, p.getOwnedNamesInBuffer(address)...)
+
+	result := make([]string, 0, len(candidates))
+	visited := make(map[string]bool)
+	for _, name := range candidates {
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		// 可能已经在缓存中转移给了别人
+		reg := p.GetNameRegisterInfo(name)
+		if reg != nil && reg.Owner == address {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// 地址设置的主名字，没有设置时返回空
+func (p *NameService) GetPrimaryName(address string) string {
+	name, ok := p.getPrimaryNameInBuffer(address)
+	if ok {
+		return name
+	}
+
+	err := p.db.View(func(txn *badger.Txn) error {
+		var err error
+		name, err = loadStringFromDB(GetPrimaryNameKey(address), txn)
+		return err
+	})
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// 在输出 txid:vout 中的名字，没有时返回空
+func (p *NameService) GetNameByLocation(location string) string {
+	name, ok := p.getLocationInBuffer(location)
+	if ok {
+		return name
+	}
+
+	err := p.db.View(func(txn *badger.Txn) error {
+		var err error
+		name, err = loadStringFromDB(GetLocationKey(location), txn)
+		return err
+	})
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// 和skeleton相同的第一个注册的名字，后面注册的形似名字只记录ConfusableWith，不会替换它
+func (p *NameService) GetNameBySkeleton(skeleton string) string {
+	reg := p.getSkeletonInBuffer(skeleton)
+	if reg != nil {
+		return reg.Name
+	}
+
+	var name string
+	err := p.db.View(func(txn *badger.Txn) error {
+		var err error
+		name, err = loadSkeletonFromDB(skeleton, txn)
+		return err
+	})
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
 // 按照铸造时间
 func (p *NameService) GetNames(start, limit int) []string {
 	result := make([]string, 0)
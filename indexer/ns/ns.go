@@ -20,7 +20,25 @@ type NameService struct {
 
 	// 状态变迁
 	nameAdded []*NameRegister // 保持顺序
+	events    []*NameEvent    // 保持顺序
+	tickers   []*Brc20Ticker  // 保持顺序
+	// 缓存中名字铭文的位置，空字符串表示数据库中的位置已经被花费
+	locations map[string]string
 
+	// 铭文编号
+	numbers   InscriptionNumbers
+	satEvents []*SatEvent // 保持顺序
+	// 缓存中聪的位置，nil表示数据库中的位置已经被花费
+	sats map[string]*common.SatInscriptions
+	// 所有铭文的位置，nil表示数据库中的位置已经被花费
+	inscMoves     []*InscriptionMove // 保持顺序
+	inscLocations map[string][]string
+	children      []*InscriptionChild // 保持顺序
+	// 用于解析delegate，需要时从铭文所在的区块读取内容
+	revealAdded  []*InscriptionReveal // 保持顺序
+	reveals      map[string]int
+	contentAdded []*InscriptionContent // 保持顺序
+	contents     map[string]*InscriptionContent
 }
 
 func NewNameService(db *badger.DB) *NameService {
@@ -33,12 +51,23 @@ func NewNameService(db *badger.DB) *NameService {
 
 // 只能被调用一次
 func (p *NameService) Init() {
-
+	p.loadInscriptionNumbers()
 }
 
 func (p *NameService) reset() {
 	p.nameAdded = make([]*NameRegister, 0)
-
+	p.events = make([]*NameEvent, 0)
+	p.tickers = make([]*Brc20Ticker, 0)
+	p.locations = make(map[string]string)
+	p.satEvents = make([]*SatEvent, 0)
+	p.sats = make(map[string]*common.SatInscriptions)
+	p.inscMoves = make([]*InscriptionMove, 0)
+	p.inscLocations = make(map[string][]string)
+	p.children = make([]*InscriptionChild, 0)
+	p.revealAdded = make([]*InscriptionReveal, 0)
+	p.reveals = make(map[string]int)
+	p.contentAdded = make([]*InscriptionContent, 0)
+	p.contents = make(map[string]*InscriptionContent)
 }
 
 func (p *NameService) Clone() *NameService {
@@ -46,12 +75,87 @@ func (p *NameService) Clone() *NameService {
 
 	newInst.nameAdded = make([]*NameRegister, len(p.nameAdded))
 	copy(newInst.nameAdded, p.nameAdded)
+	newInst.events = make([]*NameEvent, len(p.events))
+	copy(newInst.events, p.events)
+	newInst.tickers = make([]*Brc20Ticker, len(p.tickers))
+	copy(newInst.tickers, p.tickers)
+	for k, v := range p.locations {
+		newInst.locations[k] = v
+	}
+	newInst.numbers = p.numbers
+	newInst.satEvents = make([]*SatEvent, len(p.satEvents))
+	copy(newInst.satEvents, p.satEvents)
+	for k, v := range p.sats {
+		newInst.sats[k] = v
+	}
+	newInst.inscMoves = make([]*InscriptionMove, len(p.inscMoves))
+	copy(newInst.inscMoves, p.inscMoves)
+	for k, v := range p.inscLocations {
+		newInst.inscLocations[k] = v
+	}
+	newInst.children = make([]*InscriptionChild, len(p.children))
+	copy(newInst.children, p.children)
+	newInst.revealAdded = make([]*InscriptionReveal, len(p.revealAdded))
+	copy(newInst.revealAdded, p.revealAdded)
+	for k, v := range p.reveals {
+		newInst.reveals[k] = v
+	}
+	newInst.contentAdded = make([]*InscriptionContent, len(p.contentAdded))
+	copy(newInst.contentAdded, p.contentAdded)
+	for k, v := range p.contents {
+		newInst.contents[k] = v
+	}
 
 	return newInst
 }
 
 func (p *NameService) Subtract(another *NameService) {
 	p.nameAdded = p.nameAdded[len(another.nameAdded):]
+	p.events = p.events[len(another.events):]
+	p.tickers = p.tickers[len(another.tickers):]
+	p.satEvents = p.satEvents[len(another.satEvents):]
+	p.inscMoves = p.inscMoves[len(another.inscMoves):]
+	p.children = p.children[len(another.children):]
+	p.revealAdded = p.revealAdded[len(another.revealAdded):]
+	p.contentAdded = p.contentAdded[len(another.contentAdded):]
+	p.rebuildLocations()
+}
+
+// 已经写入数据库的部分不需要缓存
+func (p *NameService) rebuildLocations() {
+	p.locations = make(map[string]string)
+	for _, reg := range p.nameAdded {
+		if reg.Location != "" {
+			p.locations[reg.Location] = reg.Name
+		}
+	}
+	for _, event := range p.events {
+		if event.Type == NAME_EVENT_TRANSFER {
+			p.locations[event.OldLocation] = ""
+			if event.Location != "" {
+				p.locations[event.Location] = event.Name
+			}
+		}
+	}
+
+	p.sats = make(map[string]*common.SatInscriptions)
+	for _, event := range p.satEvents {
+		p.applySatEvent(event)
+	}
+
+	p.inscLocations = make(map[string][]string)
+	for _, move := range p.inscMoves {
+		p.applyInscriptionMove(move)
+	}
+
+	p.reveals = make(map[string]int)
+	for _, reveal := range p.revealAdded {
+		p.reveals[reveal.Txid] = reveal.Height
+	}
+	p.contents = make(map[string]*InscriptionContent)
+	for _, content := range p.contentAdded {
+		p.contents[content.InscriptionId] = content
+	}
 }
 
 // 每个Register都调用
@@ -59,6 +163,141 @@ func (p *NameService) NameRegister(reg *NameRegister) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 	p.nameAdded = append(p.nameAdded, reg)
+	if reg.Location != "" {
+		p.locations[reg.Location] = reg.Name
+	}
+}
+
+func (p *NameService) DeployTicker(ticker *Brc20Ticker) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.tickers = append(p.tickers, ticker)
+}
+
+func (p *NameService) getTickerInBuffer(ticker string) *Brc20Ticker {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for _, t := range p.tickers {
+		if t.Ticker == ticker {
+			return t
+		}
+	}
+	return nil
+}
+
+// value为空时删除
+func (p *NameService) SetKeyValue(name, key, value, inscriptionId string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, &NameEvent{
+		Type:          NAME_EVENT_KV,
+		Name:          name,
+		Key:           key,
+		Value:         value,
+		InscriptionId: inscriptionId,
+	})
+}
+
+func (p *NameService) SetPrimaryName(address, name, inscriptionId string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, &NameEvent{
+		Type:          NAME_EVENT_PRIMARY,
+		Name:          name,
+		Address:       address,
+		InscriptionId: inscriptionId,
+	})
+}
+
+// 名字铭文从oldLocation转移到location，location为空表示作为手续费被烧掉
+func (p *NameService) Transfer(name, oldLocation, location, address string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, &NameEvent{
+		Type:        NAME_EVENT_TRANSFER,
+		Name:        name,
+		Address:     address,
+		Location:    location,
+		OldLocation: oldLocation,
+	})
+	p.locations[oldLocation] = ""
+	if location != "" {
+		p.locations[location] = name
+	}
+}
+
+func (p *NameService) getLocationInBuffer(location string) (string, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	name, ok := p.locations[location]
+	return name, ok
+}
+
+// 最新的转移
+func (p *NameService) getTransferInBuffer(name string) *NameEvent {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for i := len(p.events) - 1; i >= 0; i-- {
+		event := p.events[i]
+		if event.Type == NAME_EVENT_TRANSFER && event.Name == name {
+			return event
+		}
+	}
+	return nil
+}
+
+func (p *NameService) getPrimaryNameInBuffer(address string) (string, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for i := len(p.events) - 1; i >= 0; i-- {
+		event := p.events[i]
+		if event.Type == NAME_EVENT_PRIMARY && event.Address == address {
+			return event.Name, true
+		}
+	}
+	return "", false
+}
+
+// 缓存中可能属于address的名字，需要再检查当前的持有者
+func (p *NameService) getOwnedNamesInBuffer(address string) []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	result := make([]string, 0)
+	for _, reg := range p.nameAdded {
+		if reg.Owner == address {
+			result = append(result, reg.Name)
+		}
+	}
+	for _, event := range p.events {
+		if event.Type == NAME_EVENT_TRANSFER && event.Address == address {
+			result = append(result, event.Name)
+		}
+	}
+	return result
+}
+
+// 按发生顺序
+func (p *NameService) getKeyValuesInBuffer(name string) []*NameEvent {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	result := make([]*NameEvent, 0)
+	for _, event := range p.events {
+		if event.Type == NAME_EVENT_KV && event.Name == name {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+func (p *NameService) getSkeletonInBuffer(skeleton string) *NameRegister {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for _, reg := range p.nameAdded {
+		if reg.Skeleton == skeleton && reg.ConfusableWith == "" {
+			return reg
+		}
+	}
+	return nil
 }
 
 func (p *NameService) getNameInBuffer(name string) *NameRegister {
@@ -83,24 +322,130 @@ func (p *NameService) UpdateDB() {
 	wb := p.db.NewWriteBatch()
 	defer wb.Cancel()
 
+	// 这一批写入的名字，转移时需要修改
+	values := make(map[string]*NameValueInDB)
+
 	// index: name
 	for _, name := range p.nameAdded {
 		key := GetNameKey(name.Name)
-		value := NameValueInDB{
-			NftId: name.Nft.Base.Id,
-			Sat:   name.Nft.Base.Sat,
-			Name:  name.Name,
+		value := &NameValueInDB{
+			NftId:          name.Nft.Base.Id,
+			Sat:            name.Nft.Base.Sat,
+			Name:           name.Name,
+			Original:       name.Original,
+			Skeleton:       name.Skeleton,
+			ConfusableWith: name.ConfusableWith,
+			Owner:          name.Owner,
+			Location:       name.Location,
+			InscriptionId:  name.Nft.Base.InscriptionId,
 		}
-		err := common.SetDBWithProto3([]byte(key), &value, wb)
+		values[name.Name] = value
+		err := common.SetDBWithProto3([]byte(key), value, wb)
 		//err := common.SetDB([]byte(key), &value, wb)
 		if err != nil {
 			common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
 		}
 
+		if name.Location != "" {
+			key = GetLocationKey(name.Location)
+			err = common.SetDB([]byte(key), name.Name, wb)
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+			}
+		}
+		if name.Owner != "" {
+			key = GetOwnerKey(name.Owner, name.Name)
+			err = wb.Set([]byte(key), nil)
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+			}
+		}
+
+		// 形似的名字只保留第一个
+		if name.Skeleton != "" && name.ConfusableWith == "" {
+			key = GetSkeletonKey(name.Skeleton)
+			err = common.SetDB([]byte(key), name.Name, wb)
+			if err != nil {
+				common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+			}
+		}
+
 		// buckNames[int(name.Id)] = &BuckValue{Name: name.Name, Sat: name.Nft.Base.Sat}
 	}
 
+	for _, event := range p.events {
+		var key string
+		var err error
+		switch event.Type {
+		case NAME_EVENT_KV:
+			key = GetKeyValueKey(event.Name, event.Key)
+			if event.Value == "" {
+				err = wb.Delete([]byte(key))
+			} else {
+				err = common.SetDB([]byte(key), &common.KeyValueInDB{Value: event.Value, InscriptionId: event.InscriptionId}, wb)
+			}
+		case NAME_EVENT_PRIMARY:
+			key = GetPrimaryNameKey(event.Address)
+			err = common.SetDB([]byte(key), event.Name, wb)
+		case NAME_EVENT_TRANSFER:
+			value, ok := values[event.Name]
+			if !ok {
+				value = &NameValueInDB{}
+				err = p.db.View(func(txn *badger.Txn) error {
+					return loadNameFromDB(event.Name, value, txn)
+				})
+				if err != nil {
+					common.Log.Panicf("NameService->UpdateDB load name %s failed. %v", event.Name, err)
+				}
+				values[event.Name] = value
+			}
+			if value.Owner != "" {
+				err = wb.Delete([]byte(GetOwnerKey(value.Owner, event.Name)))
+				if err != nil {
+					common.Log.Panicf("NameService->UpdateDB Error deleting owner of %s in db %v", event.Name, err)
+				}
+			}
+			value.Owner = event.Address
+			value.Location = event.Location
+			if value.Owner != "" {
+				err = wb.Set([]byte(GetOwnerKey(value.Owner, event.Name)), nil)
+				if err != nil {
+					common.Log.Panicf("NameService->UpdateDB Error setting owner of %s in db %v", event.Name, err)
+				}
+			}
+			key = GetNameKey(event.Name)
+			err = common.SetDBWithProto3([]byte(key), value, wb)
+			if err == nil {
+				err = wb.Delete([]byte(GetLocationKey(event.OldLocation)))
+			}
+			if err == nil && event.Location != "" {
+				key = GetLocationKey(event.Location)
+				err = common.SetDB([]byte(key), event.Name, wb)
+			}
+		}
+		if err != nil {
+			common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+		}
+	}
+
+	for _, ticker := range p.tickers {
+		key := GetTickerKey(ticker.Ticker)
+		err := common.SetDB([]byte(key), ticker, wb)
+		if err != nil {
+			common.Log.Panicf("NameService->UpdateDB Error setting %s in db %v", key, err)
+		}
+	}
+
+	p.updateInscriptionDB(wb)
+	p.updateInscriptionRelationDB(wb)
+	p.updateInscriptionContentDB(wb)
+
+	err := wb.Flush()
+	if err != nil {
+		common.Log.Panicf("NameService->UpdateDB Error flushing db %v", err)
+	}
+
 	// reset memory buffer
-	p.nameAdded = make([]*NameRegister, 0)
+	p.reset()
 	common.Log.Infof("NameService->UpdateDB takes %v", time.Since(startTime))
 }
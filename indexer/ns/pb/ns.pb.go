@@ -25,10 +25,16 @@ type NameValueInDB struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	NftId int64  `protobuf:"varint,1,opt,name=nftId,proto3" json:"nftId,omitempty"`
-	Id    int64  `protobuf:"varint,2,opt,name=Id,proto3" json:"Id,omitempty"`
-	Sat   int64  `protobuf:"varint,3,opt,name=sat,proto3" json:"sat,omitempty"`
-	Name  string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	NftId          int64  `protobuf:"varint,1,opt,name=nftId,proto3" json:"nftId,omitempty"`
+	Id             int64  `protobuf:"varint,2,opt,name=Id,proto3" json:"Id,omitempty"`
+	Sat            int64  `protobuf:"varint,3,opt,name=sat,proto3" json:"sat,omitempty"`
+	Name           string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Original       string `protobuf:"bytes,5,opt,name=original,proto3" json:"original,omitempty"`
+	Skeleton       string `protobuf:"bytes,6,opt,name=skeleton,proto3" json:"skeleton,omitempty"`
+	ConfusableWith string `protobuf:"bytes,7,opt,name=confusableWith,proto3" json:"confusableWith,omitempty"`
+	Owner          string `protobuf:"bytes,8,opt,name=owner,proto3" json:"owner,omitempty"`
+	Location       string `protobuf:"bytes,9,opt,name=location,proto3" json:"location,omitempty"`
+	InscriptionId  string `protobuf:"bytes,10,opt,name=inscriptionId,proto3" json:"inscriptionId,omitempty"`
 }
 
 func (x *NameValueInDB) Reset() {
@@ -91,19 +97,73 @@ func (x *NameValueInDB) GetName() string {
 	return ""
 }
 
+func (x *NameValueInDB) GetOriginal() string {
+	if x != nil {
+		return x.Original
+	}
+	return ""
+}
+
+func (x *NameValueInDB) GetSkeleton() string {
+	if x != nil {
+		return x.Skeleton
+	}
+	return ""
+}
+
+func (x *NameValueInDB) GetConfusableWith() string {
+	if x != nil {
+		return x.ConfusableWith
+	}
+	return ""
+}
+
+func (x *NameValueInDB) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *NameValueInDB) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *NameValueInDB) GetInscriptionId() string {
+	if x != nil {
+		return x.InscriptionId
+	}
+	return ""
+}
+
 var File_indexer_ns_pb_ns_proto protoreflect.FileDescriptor
 
 var file_indexer_ns_pb_ns_proto_rawDesc = []byte{
 	0x0a, 0x16, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x65, 0x72, 0x2f, 0x6e, 0x73, 0x2f, 0x70, 0x62, 0x2f,
 	0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x70, 0x62, 0x2e, 0x69, 0x6e, 0x64,
-	0x65, 0x78, 0x65, 0x72, 0x2e, 0x6e, 0x73, 0x22, 0x5b, 0x0a, 0x0d, 0x4e, 0x61, 0x6d, 0x65, 0x56,
-	0x61, 0x6c, 0x75, 0x65, 0x49, 0x6e, 0x44, 0x42, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x66, 0x74, 0x49,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6e, 0x66, 0x74, 0x49, 0x64, 0x12, 0x0e,
-	0x0a, 0x02, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x64, 0x12, 0x10,
-	0x0a, 0x03, 0x73, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x61, 0x74,
-	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
-	0x6e, 0x61, 0x6d, 0x65, 0x42, 0x10, 0x5a, 0x0e, 0x2f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x65, 0x72,
-	0x2f, 0x6e, 0x73, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x78, 0x65, 0x72, 0x2e, 0x6e, 0x73, 0x22, 0x93, 0x02, 0x0a, 0x0d, 0x4e, 0x61, 0x6d, 0x65,
+	0x56, 0x61, 0x6c, 0x75, 0x65, 0x49, 0x6e, 0x44, 0x42, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x66, 0x74,
+	0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6e, 0x66, 0x74, 0x49, 0x64, 0x12,
+	0x0e, 0x0a, 0x02, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x64, 0x12,
+	0x10, 0x0a, 0x03, 0x73, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x61,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61,
+	0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61,
+	0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x6b, 0x65, 0x6c, 0x65, 0x74, 0x6f, 0x6e, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x6b, 0x65, 0x6c, 0x65, 0x74, 0x6f, 0x6e, 0x12, 0x26, 0x0a,
+	0x0e, 0x63, 0x6f, 0x6e, 0x66, 0x75, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x57, 0x69, 0x74, 0x68, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x63, 0x6f, 0x6e, 0x66, 0x75, 0x73, 0x61, 0x62, 0x6c,
+	0x65, 0x57, 0x69, 0x74, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x6c,
+	0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c,
+	0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x69, 0x6e, 0x73, 0x63, 0x72,
+	0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x69, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x42, 0x10, 0x5a,
+	0x0e, 0x2f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x65, 0x72, 0x2f, 0x6e, 0x73, 0x2f, 0x70, 0x62, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
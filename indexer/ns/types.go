@@ -6,9 +6,29 @@ import (
 )
 
 const (
-	DB_PREFIX_NAME = "r-" // name  NameRegister
-	DB_PREFIX_KV   = "k-" // key-value  KeyValueInDB
-	DB_PREFIX_BUCK = "bk-"
+	DB_PREFIX_NAME     = "r-" // name  NameRegister
+	DB_PREFIX_KV       = "k-" // key-value  KeyValueInDB
+	DB_PREFIX_BUCK     = "bk-"
+	DB_PREFIX_SKELETON = "sk-"  // skeleton  name，只记录第一个注册的名字
+	DB_PREFIX_PRIMARY  = "pn-"  // address  name
+	DB_PREFIX_LOCATION = "loc-" // utxo  name，名字铭文当前所在的输出
+	DB_PREFIX_OWNER    = "o-"   // address-name  name
+	DB_PREFIX_TICKER   = "tk-"  // ticker  Brc20Ticker，和名字是不同的namespace
+	DB_PREFIX_SAT      = "s-"   // utxo  SatInscriptions，输出第一个聪上的铭文
+	DB_PREFIX_INSC_LOC = "il-"  // utxo  []inscriptionId
+	DB_PREFIX_CHILD    = "ch-"  // parent-child  order
+	DB_PREFIX_PARENT   = "pa-"  // child-parent  order
+	DB_PREFIX_CONTENT  = "ct-"  // inscriptionId  InscriptionContent，只有被delegate引用的铭文
+	DB_PREFIX_REVEAL   = "rv-"  // txid  height，有铭文的交易所在的区块
+
+	DB_KEY_INSCRIPTION_NUMBERS = "insc-numbers" // InscriptionNumbers
+)
+
+// 名字注册以后的状态变化
+const (
+	NAME_EVENT_KV       = 1
+	NAME_EVENT_PRIMARY  = 2
+	NAME_EVENT_TRANSFER = 3
 )
 
 type NameValueInDB = pb.NameValueInDB
@@ -16,5 +36,38 @@ type NameValueInDB = pb.NameValueInDB
 // 由nft维持实时状态
 type NameRegister struct {
 	Nft  *common.Nft
-	Name string
+	Name string // 规范化后的名字
+	// 铭刻的原始名字
+	Original string
+	Skeleton string
+	// 注册时已经存在的形似名字
+	ConfusableWith string
+	// 持有名字铭文的地址，以及铭文所在的输出 txid:vout
+	Owner    string
+	Location string
+}
+
+// 按发生的顺序写入数据库
+type NameEvent struct {
+	Type          int
+	Name          string
+	Key           string // NAME_EVENT_KV
+	Value         string // NAME_EVENT_KV，为空时删除
+	InscriptionId string
+	Address       string // NAME_EVENT_PRIMARY，NAME_EVENT_TRANSFER的新地址
+	Location      string // NAME_EVENT_TRANSFER的新位置
+	OldLocation   string // NAME_EVENT_TRANSFER
+}
+
+// brc-20 deploy 的信息，数量保持铭文中的字符串
+type Brc20Ticker struct {
+	Ticker         string // 小写
+	OriginalTicker string
+	Max            string
+	Limit          string
+	Decimal        int
+	SelfMint       bool
+	InscriptionId  string
+	Deployer       string
+	Height         int
 }
@@ -2,18 +2,123 @@ package indexer
 
 import (
 	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/mempool"
 )
 
+// name 可以是unicode形式，也可以是 xn-- 形式
 func (b *IndexerMgr) GetNameInfo(name string) *common.NameInfo {
-	reg := b.ns.GetNameRegisterInfo(name)
+	if common.IsACEName(name) {
+		unicode, err := common.NameFromASCII(name)
+		if err != nil {
+			common.Log.Errorf("GetNameInfo %v", err)
+			return nil
+		}
+		name = unicode
+	}
+	reg := b.getNameRegister(name, b.nextHeight())
 	if reg == nil {
 		common.Log.Errorf("GetNameRegisterInfo %s failed", name)
 		return nil
 	}
 
-	return nil
+	info := &common.NameInfo{Name: reg.Name, Owner: reg.Owner}
+	if reg.Nft != nil {
+		info.Base = reg.Nft.Base
+		info.Id = reg.Nft.Base.Id
+	}
+	info.Punycode, _ = common.NameToASCII(reg.Name)
+	info.Restriction = b.getRestriction(reg.Name, b.nextHeight())
+	info.KVs = b.ns.GetKeyValues(reg.Name)
+	return info
+}
+
+// 地址当前持有的名字，包括bitmap等其他namespace
+func (b *IndexerMgr) GetNamesByOwner(address string) []string {
+	return b.ns.GetNamesByOwner(address)
+}
+
+// 地址的主名字，名字转移以后失效
+func (b *IndexerMgr) GetPrimaryName(address string) string {
+	name := b.ns.GetPrimaryName(address)
+	if name == "" {
+		return ""
+	}
+	reg := b.ns.GetNameRegisterInfo(name)
+	if reg == nil || reg.Owner != address {
+		return ""
+	}
+	return name
+}
+
+// 用于DNS/DoH查询，qname是DNS中的ASCII形式，不能映射到已注册名字时返回nil
+func (b *IndexerMgr) LookupDNSName(qname string) *common.NameInfo {
+	name, err := common.NameFromASCII(qname)
+	if err != nil {
+		common.Log.Debugf("LookupDNSName %v", err)
+		return nil
+	}
+	info := b.GetNameInfo(name)
+	if info == nil {
+		return nil
+	}
+	// 只能返回可以在DNS中表示的名字
+	if info.Punycode == "" {
+		common.Log.Debugf("LookupDNSName %s can't be represented in IDNA", info.Name)
+		return nil
+	}
+	if info.Restriction != nil && info.Restriction.Type == common.NAME_BLOCKED {
+		common.Log.Debugf("LookupDNSName %s is blocked", info.Name)
+		return nil
+	}
+	return info
 }
 
 func (b *IndexerMgr) GetNames(start, limit int) []string {
 	return b.ns.GetNames(start, limit)
 }
+
+// 内存池中还未确认的注册，按首次发现时间排序，没有启用内存池监控时返回nil
+func (b *IndexerMgr) GetPendingNameRegisters(name string) []*mempool.PendingRegister {
+	if b.mempool == nil {
+		return nil
+	}
+	return b.mempool.GetPendingRegisters(b.normalizeNameAtHeight(name, b.nextHeight()))
+}
+
+func (b *IndexerMgr) GetPendingNames() []string {
+	if b.mempool == nil {
+		return nil
+	}
+	return b.mempool.GetPendingNames()
+}
+
+type NameAvailability struct {
+	Name string `json:"name"` // 规范化后的名字
+	// DNS中使用的ASCII形式，名字不能表示为IDNA时为空
+	Punycode     string `json:"punycode,omitempty"`
+	Available    bool   `json:"available"`
+	Reason       string `json:"reason,omitempty"`       // common.NAME_*
+	RegisteredBy string `json:"registeredBy,omitempty"` // 已注册时的铭文id，可能未知
+	// 形似的已注册名字，flag模式下依然可以注册
+	ConfusableWith string                     `json:"confusableWith,omitempty"`
+	Restriction    *common.NameRestriction    `json:"restriction,omitempty"`
+	Pending        []*mempool.PendingRegister `json:"pending,omitempty"`
+}
+
+// 检查名字是否可以注册，使用和handleSnsName一样的规则
+func (b *IndexerMgr) CheckNameAvailability(name string) *NameAvailability {
+	result := b.checkSnsNameRegister(name, b.nextHeight())
+	result.Punycode, _ = common.NameToASCII(result.Name)
+	if result.Reason != "" {
+		return result
+	}
+
+	result.Pending = b.GetPendingNameRegisters(result.Name)
+	if len(result.Pending) > 0 {
+		result.Reason = common.NAME_PENDING
+		return result
+	}
+
+	result.Available = true
+	return result
+}
@@ -0,0 +1,60 @@
+package protocol
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/OLProtocol/ordx/common"
+)
+
+// 不是元协议，内容为 <height>.bitmap 的纯文本铭文
+const (
+	PROTOCOL_BITMAP  = "bitmap"
+	BITMAP_NAMESPACE = "bitmap"
+)
+
+// 不能有前导0
+var bitmapRegexp = regexp.MustCompile(`^(0|[1-9][0-9]*)\.bitmap$`)
+
+// 返回district的区块高度
+func ParseBitmap(content []byte) (int, bool) {
+	if !bitmapRegexp.Match(content) {
+		return 0, false
+	}
+	height, err := strconv.Atoi(string(content[:len(content)-len(".bitmap")]))
+	if err != nil {
+		return 0, false
+	}
+	return height, true
+}
+
+func GetBitmapName(height int) string {
+	return fmt.Sprintf("%d.%s", height, BITMAP_NAMESPACE)
+}
+
+// 每个区块高度只有第一个铭文有效，高度不能超过铭文所在的区块
+type BitmapHandler struct{}
+
+func (p *BitmapHandler) Protocol() string {
+	return PROTOCOL_BITMAP
+}
+
+func (p *BitmapHandler) ParseName(env *Envelope) (string, bool) {
+	height, ok := ParseBitmap(env.Fields[common.FIELD_CONTENT])
+	if !ok {
+		return "", false
+	}
+	return GetBitmapName(height), true
+}
+
+func (p *BitmapHandler) Handle(env *Envelope, tx *TxContext, names NameService) {
+	height, ok := ParseBitmap(env.Fields[common.FIELD_CONTENT])
+	if !ok {
+		return
+	}
+	if height > tx.Block.Height {
+		return
+	}
+	names.RegisterNamespaceName(GetBitmapName(height), env.Nft, tx)
+}
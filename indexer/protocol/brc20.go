@@ -0,0 +1,125 @@
+package protocol
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/ns"
+)
+
+const (
+	BRC20_TICKER_LEN           = 4
+	BRC20_SELF_MINT_TICKER_LEN = 5 // 只能是self mint
+	BRC20_MAX_DECIMAL          = 18
+
+	// 主网上5字节ticker和self mint的生效高度
+	BRC20_SELF_MINT_HEIGHT_MAINNET = 837090
+)
+
+var brc20MaxAmount = new(big.Int).SetUint64(^uint64(0))
+
+// brc-20 deploy 的ticker，有自己的namespace，不占用名字
+type Brc20Handler struct {
+	SelfMintHeight int // 5字节ticker从这个高度开始有效
+}
+
+func (p *Brc20Handler) Protocol() string {
+	return PROTOCOL_BRC20
+}
+
+func (p *Brc20Handler) Handle(env *Envelope, tx *TxContext, names NameService) {
+	deploy := common.ParseBrc20DeployContent(string(env.Fields[common.FIELD_CONTENT]))
+	if deploy == nil || deploy.Op != "deploy" {
+		return
+	}
+
+	height := int(env.Nft.Base.BlockHeight)
+	ticker, err := p.parseDeploy(deploy, height)
+	if err != "" {
+		common.Log.Warnf("%s brc-20 deploy %s: %s", env.Nft.Base.InscriptionId, deploy.Ticker, err)
+		return
+	}
+	ticker.InscriptionId = env.Nft.Base.InscriptionId
+	ticker.Deployer, _ = tx.Receiver()
+	ticker.Height = height
+
+	names.DeployTicker(ticker)
+}
+
+// 检查deploy的参数，返回错误原因
+func (p *Brc20Handler) parseDeploy(deploy *common.Brc20DeployContent, height int) (*ns.Brc20Ticker, string) {
+	selfMint := false
+	switch len(deploy.Ticker) {
+	case BRC20_TICKER_LEN:
+	case BRC20_SELF_MINT_TICKER_LEN:
+		if height < p.SelfMintHeight {
+			return nil, "5-byte ticker is not activated"
+		}
+		if deploy.SelfMint != "true" {
+			return nil, "5-byte ticker must be self mint"
+		}
+		selfMint = true
+	default:
+		return nil, "invalid ticker length"
+	}
+
+	dec := BRC20_MAX_DECIMAL
+	if deploy.Dec != "" {
+		var err error
+		dec, err = strconv.Atoi(deploy.Dec)
+		if err != nil || dec < 0 || dec > BRC20_MAX_DECIMAL {
+			return nil, "invalid dec"
+		}
+	}
+
+	max, ok := parseBrc20Amount(deploy.Max, dec)
+	if !ok {
+		return nil, "invalid max"
+	}
+	// self mint 的max可以是0，表示没有上限
+	if max.Sign() == 0 && !selfMint {
+		return nil, "invalid max"
+	}
+	lim := deploy.Max
+	if deploy.Lim != "" {
+		if _, ok := parseBrc20Amount(deploy.Lim, dec); !ok {
+			return nil, "invalid lim"
+		}
+		lim = deploy.Lim
+	}
+
+	return &ns.Brc20Ticker{
+		Ticker:         GetBrc20Ticker(deploy.Ticker),
+		OriginalTicker: deploy.Ticker,
+		Max:            deploy.Max,
+		Limit:          lim,
+		Decimal:        dec,
+		SelfMint:       selfMint,
+	}, ""
+}
+
+// ticker不区分大小写
+func GetBrc20Ticker(ticker string) string {
+	return strings.ToLower(ticker)
+}
+
+// 十进制数，小数位数不超过dec，整数部分不超过uint64，返回乘以10^dec后的值
+func parseBrc20Amount(amount string, dec int) (*big.Int, bool) {
+	integer, fraction, found := strings.Cut(amount, ".")
+	if integer == "" || (found && fraction == "") || len(fraction) > dec {
+		return nil, false
+	}
+	for _, c := range integer + fraction {
+		if c < '0' || c > '9' {
+			return nil, false
+		}
+	}
+	value, ok := new(big.Int).SetString(integer, 10)
+	if !ok || value.Cmp(brc20MaxAmount) > 0 {
+		return nil, false
+	}
+	value, _ = value.SetString(integer+fraction+strings.Repeat("0", dec-len(fraction)), 10)
+	return value, true
+}
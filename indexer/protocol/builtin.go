@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/ns"
+)
+
+const (
+	PROTOCOL_SNS     = "sns"
+	PROTOCOL_BRC20   = "brc-20"
+	PROTOCOL_BTCNAME = "btcname"
+	PROTOCOL_TEXT    = "" // 没有元协议的纯文本
+)
+
+// avatar 操作设置的属性
+const NAME_KEY_AVATAR = "avatar"
+
+func BuiltinHandlers() []Handler {
+	return []Handler{
+		&SnsHandler{},
+		&Brc20Handler{},
+		&BtcnameHandler{},
+		&TextHandler{},
+		&BitmapHandler{},
+	}
+}
+
+// 注册ParseName返回的名字
+func registerParsedName(parser NameParser, env *Envelope, tx *TxContext, names NameService) {
+	name, ok := parser.ParseName(env)
+	if ok {
+		names.RegisterName(name, env.Nft, tx)
+	}
+}
+
+// 只有名字的持有者可以修改：交易需要花费名字铭文所在的输出，铭文铸造到哪个地址都可以伪造。
+// 处理铭文之前名字已经随交易转移，这时名字铭文在这个交易的输出中
+func checkNameOwner(op string, name string, env *Envelope, tx *TxContext, names NameService) *ns.NameRegister {
+	reg := names.GetNameRegister(name)
+	if reg == nil {
+		common.Log.Warnf("%s %s: Name %s not exist", env.Nft.Base.InscriptionId, op, name)
+		return nil
+	}
+	if reg.Location == "" || reg.Owner == "" {
+		common.Log.Warnf("%s %s: Name %s has no owner", env.Nft.Base.InscriptionId, op, name)
+		return nil
+	}
+	if !tx.Spends(reg.Location) && !strings.HasPrefix(reg.Location, tx.Tx.Txid+":") {
+		common.Log.Warnf("%s %s: Name %s at %s is not spent by the transaction",
+			env.Nft.Base.InscriptionId, op, name, reg.Location)
+		return nil
+	}
+	return reg
+}
+
+// {"p":"sns","op":"reg","name":"xxx"}
+// {"p":"sns","op":"update","name":"xxx","kvs":["key=value"]} 或者 {"p":"sns","op":"update","name":"xxx","key":"value"}
+// {"p":"sns","op":"primary","name":"xxx"}
+// {"p":"sns","op":"avatar","name":"xxx","avatar":"inscriptionId"}
+type SnsHandler struct{}
+
+func (p *SnsHandler) Protocol() string {
+	return PROTOCOL_SNS
+}
+
+func (p *SnsHandler) ParseName(env *Envelope) (string, bool) {
+	domain := common.ParseDomainContent(string(env.Fields[common.FIELD_CONTENT]))
+	if domain == nil {
+		domain = common.ParseDomainContent(string(env.Content))
+	}
+	if domain != nil {
+		switch domain.Op {
+		case "reg":
+			return domain.Name, true
+		}
+	}
+	return "", false
+}
+
+func (p *SnsHandler) Handle(env *Envelope, tx *TxContext, names NameService) {
+	content := env.Fields[common.FIELD_CONTENT]
+	var base common.OrdxBaseContent
+	if json.Unmarshal(content, &base) != nil || base.Op == "" {
+		content = env.Content
+		if json.Unmarshal(content, &base) != nil {
+			return
+		}
+	}
+
+	switch base.Op {
+	case "reg":
+		registerParsedName(p, env, tx, names)
+	case "update":
+		p.handleUpdate(string(content), env, tx, names)
+	case "primary", "avatar":
+		p.handlePrimary(base.Op, content, env, tx, names)
+	}
+}
+
+func (p *SnsHandler) handleUpdate(content string, env *Envelope, tx *TxContext, names NameService) {
+	update := common.ParseUpdateContent(content)
+	if update == nil || len(update.KVs) == 0 {
+		return
+	}
+	if checkNameOwner(update.Op, update.Name, env, tx, names) == nil {
+		return
+	}
+	kvs := make(map[string]string)
+	for k, v := range update.KVs {
+		k = strings.TrimSpace(k)
+		if k != "" {
+			kvs[k] = strings.TrimSpace(v)
+		}
+	}
+	names.SetKeyValues(update.Name, kvs, env.Nft)
+}
+
+func (p *SnsHandler) handlePrimary(op string, content []byte, env *Envelope, tx *TxContext, names NameService) {
+	var primary common.PrimaryNameBaseContent
+	err := json.Unmarshal(content, &primary)
+	if err != nil {
+		return
+	}
+	reg := checkNameOwner(op, primary.Name, env, tx, names)
+	if reg == nil {
+		return
+	}
+	switch op {
+	case "primary":
+		names.SetPrimaryName(reg.Owner, primary.Name, env.Nft)
+	case "avatar":
+		names.SetKeyValues(primary.Name, map[string]string{NAME_KEY_AVATAR: primary.Avatar}, env.Nft)
+	}
+}
+
+// 修改已注册名字的属性
+type BtcnameHandler struct{}
+
+func (p *BtcnameHandler) Protocol() string {
+	return PROTOCOL_BTCNAME
+}
+
+func (p *BtcnameHandler) Handle(env *Envelope, tx *TxContext, names NameService) {
+	content := common.ParseCommonContent(string(env.Fields[common.FIELD_CONTENT]))
+	if content == nil {
+		return
+	}
+	switch content.Op {
+	case "routing":
+		p.handleRouting(content, env.Nft, names)
+	}
+}
+
+func (p *BtcnameHandler) handleRouting(content *common.OrdxUpdateContentV2, nft *common.Nft, names NameService) {
+	reg := names.GetNameRegister(content.Name)
+	if reg == nil {
+		common.Log.Warnf("BtcnameHandler.handleRouting: %s, Name %s not exist", nft.Base.InscriptionId, content.Name)
+		return
+	}
+
+	// TODO
+	// 只需要当前owner持有该nft就可以修改，而不必在sat上继续铸造
+	// if nft.OwnerAddressId != reg.Nft.OwnerAddressId {
+	// 	common.Log.Warnf("BtcnameHandler.handleRouting: %s, Name %s has different owner", nft.Base.InscriptionId, content.Name)
+	// 	return
+	// }
+}
+
+// 如果content中的内容格式，符合 *.* 或者 * , 并且字段在32字节以内，符合名字规范，就把它当做一个名字来处理
+// text/plain;charset=utf-8 abc
+// 或者简单文本 xxx.xx 或者 xx
+type TextHandler struct{}
+
+func (p *TextHandler) Protocol() string {
+	return PROTOCOL_TEXT
+}
+
+func (p *TextHandler) ParseName(env *Envelope) (string, bool) {
+	return string(env.Fields[common.FIELD_CONTENT]), true
+}
+
+func (p *TextHandler) Handle(env *Envelope, tx *TxContext, names NameService) {
+	registerParsedName(p, env, tx, names)
+}
@@ -0,0 +1,331 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/ns"
+)
+
+const (
+	testOwner = "bc1powner"
+	testOther = "bc1pother"
+)
+
+// 内存中的名字服务，不检查名字规则
+type fakeNameService struct {
+	names     map[string]*ns.NameRegister
+	namespace map[string]bool // 通过RegisterNamespaceName注册的名字
+	kvs       map[string]map[string]string
+	primary   map[string]string
+	tickers   map[string]*ns.Brc20Ticker
+}
+
+func newFakeNameService() *fakeNameService {
+	return &fakeNameService{
+		names:     make(map[string]*ns.NameRegister),
+		namespace: make(map[string]bool),
+		kvs:       make(map[string]map[string]string),
+		primary:   make(map[string]string),
+		tickers:   make(map[string]*ns.Brc20Ticker),
+	}
+}
+
+func (p *fakeNameService) RegisterName(name string, nft *common.Nft, tx *TxContext) bool {
+	name = strings.ToLower(name)
+	if name == "" || p.names[name] != nil {
+		return false
+	}
+	owner, location := tx.Receiver()
+	p.names[name] = &ns.NameRegister{Nft: nft, Name: name, Owner: owner, Location: location}
+	return true
+}
+
+func (p *fakeNameService) RegisterNamespaceName(name string, nft *common.Nft, tx *TxContext) bool {
+	if !p.RegisterName(name, nft, tx) {
+		return false
+	}
+	p.namespace[name] = true
+	return true
+}
+
+func (p *fakeNameService) GetNameRegister(name string) *ns.NameRegister {
+	return p.names[strings.ToLower(name)]
+}
+
+func (p *fakeNameService) SetKeyValues(name string, kvs map[string]string, nft *common.Nft) {
+	name = strings.ToLower(name)
+	if p.kvs[name] == nil {
+		p.kvs[name] = make(map[string]string)
+	}
+	for k, v := range kvs {
+		p.kvs[name][k] = v
+	}
+}
+
+func (p *fakeNameService) SetPrimaryName(address, name string, nft *common.Nft) {
+	p.primary[address] = strings.ToLower(name)
+}
+
+func (p *fakeNameService) DeployTicker(ticker *ns.Brc20Ticker) bool {
+	if p.tickers[ticker.Ticker] != nil {
+		return false
+	}
+	p.tickers[ticker.Ticker] = ticker
+	return true
+}
+
+func (p *fakeNameService) GetTicker(ticker string) *ns.Brc20Ticker {
+	return p.tickers[GetBrc20Ticker(ticker)]
+}
+
+var testTxCount = 0
+
+// 铭文在height高度的交易中，铸造到receiver，spent是交易花费的 txid:vout
+func newTestEnvelope(content string, height int, receiver string, spent ...string) (*Envelope, *TxContext) {
+	testTxCount++
+	txid := fmt.Sprintf("%064x", testTxCount)
+	nft := &common.Nft{Base: &common.InscribeBaseContent{
+		InscriptionId: txid + "i0",
+		BlockHeight:   int32(height),
+	}}
+	fields := map[int][]byte{common.FIELD_CONTENT: []byte(content)}
+	env := &Envelope{Fields: fields, Content: fields[common.FIELD_CONTENT], Nft: nft}
+
+	tx := &common.Transaction{
+		Txid: txid,
+		Outputs: []*common.Output{{
+			Height:  height,
+			Value:   10000,
+			Address: &common.ScriptPubKey{Addresses: []string{receiver}},
+		}},
+	}
+	for _, outpoint := range spent {
+		prevTxid, vout, _ := strings.Cut(outpoint, ":")
+		input := &common.Input{Txid: prevTxid}
+		fmt.Sscanf(vout, "%d", &input.Vout)
+		tx.Inputs = append(tx.Inputs, input)
+	}
+	return env, &TxContext{Block: &common.Block{Height: height}, Tx: tx}
+}
+
+func handle(handler Handler, names NameService, content string, height int, receiver string, spent ...string) *Envelope {
+	env, tx := newTestEnvelope(content, height, receiver, spent...)
+	handler.Handle(env, tx, names)
+	return env
+}
+
+func jsonContent(v map[string]interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func TestSnsHandlerReg(t *testing.T) {
+	names := newFakeNameService()
+	handler := &SnsHandler{}
+	env := handle(handler, names, `{"p":"sns","op":"reg","name":"Alpha.sats"}`, 10, testOwner)
+
+	reg := names.GetNameRegister("alpha.sats")
+	if reg == nil || reg.Owner != testOwner || reg.Nft != env.Nft {
+		t.Fatalf("alpha.sats not registered to %s", testOwner)
+	}
+	if name, ok := handler.ParseName(env); !ok || name != "Alpha.sats" {
+		t.Fatalf("ParseName returned %s %v", name, ok)
+	}
+
+	// metaprotocol的内容在metadata中
+	env, tx := newTestEnvelope("not json", 11, testOwner)
+	env.Content = []byte(`{"p":"sns","op":"reg","name":"beta.sats"}`)
+	handler.Handle(env, tx, names)
+	if names.GetNameRegister("beta.sats") == nil {
+		t.Fatalf("beta.sats in metadata not registered")
+	}
+
+	// 不是reg操作不注册名字
+	handle(handler, names, `{"p":"sns","op":"other","name":"gamma.sats"}`, 12, testOwner)
+	if len(names.names) != 2 {
+		t.Fatalf("%d names registered, expected 2", len(names.names))
+	}
+}
+
+// 名字铭文所在的输出
+func nameLocation(names *fakeNameService, name string) string {
+	return names.GetNameRegister(name).Location
+}
+
+func TestSnsHandlerUpdate(t *testing.T) {
+	names := newFakeNameService()
+	handler := &SnsHandler{}
+	handle(handler, names, `{"p":"sns","op":"reg","name":"alpha.sats"}`, 10, testOwner)
+	location := nameLocation(names, "alpha.sats")
+
+	handle(handler, names, `{"p":"sns","op":"update","name":"alpha.sats","url":" https://a.b ","":"x"}`,
+		11, testOwner, location)
+	if kvs := names.kvs["alpha.sats"]; len(kvs) != 1 || kvs["url"] != "https://a.b" {
+		t.Fatalf("kvs %v after update", kvs)
+	}
+
+	// rarepizza使用的kvs数组格式
+	handle(handler, names, `{"p":"sns","op":"update","name":"alpha.sats","kvs":["cover=abc"]}`,
+		12, testOwner, location)
+	if kvs := names.kvs["alpha.sats"]; kvs["cover"] != "abc" {
+		t.Fatalf("kvs %v after update with kvs array", kvs)
+	}
+
+	// 没有注册的名字
+	handle(handler, names, `{"p":"sns","op":"update","name":"beta.sats","url":"x"}`, 13, testOwner)
+	if names.kvs["beta.sats"] != nil {
+		t.Fatalf("unregistered name updated")
+	}
+
+	// 其他人铸造到持有者的地址，没有花费名字铭文
+	handle(handler, names, `{"p":"sns","op":"update","name":"alpha.sats","url":"https://evil"}`,
+		14, testOwner, fmt.Sprintf("%064x:0", 99))
+	if kvs := names.kvs["alpha.sats"]; kvs["url"] != "https://a.b" {
+		t.Fatalf("kvs %v updated without spending the name", kvs)
+	}
+}
+
+func TestSnsHandlerPrimaryAndAvatar(t *testing.T) {
+	names := newFakeNameService()
+	handler := &SnsHandler{}
+	handle(handler, names, `{"p":"sns","op":"reg","name":"alpha.sats"}`, 10, testOwner)
+	location := nameLocation(names, "alpha.sats")
+
+	handle(handler, names, `{"p":"sns","op":"primary","name":"alpha.sats"}`, 11, testOwner, location)
+	if names.primary[testOwner] != "alpha.sats" {
+		t.Fatalf("primary name %s, expected alpha.sats", names.primary[testOwner])
+	}
+
+	avatar := fmt.Sprintf("%064xi0", 1)
+	handle(handler, names, jsonContent(map[string]interface{}{
+		"p": "sns", "op": "avatar", "name": "alpha.sats", "avatar": avatar,
+	}), 12, testOwner, location)
+	if names.kvs["alpha.sats"][NAME_KEY_AVATAR] != avatar {
+		t.Fatalf("avatar %s, expected %s", names.kvs["alpha.sats"][NAME_KEY_AVATAR], avatar)
+	}
+
+	// 没有注册的名字
+	handle(handler, names, `{"p":"sns","op":"primary","name":"beta.sats"}`, 13, testOther)
+	if names.primary[testOther] != "" {
+		t.Fatalf("unregistered name set as primary")
+	}
+
+	// 其他人铸造到持有者的地址，没有花费名字铭文
+	handle(handler, names, jsonContent(map[string]interface{}{
+		"p": "sns", "op": "avatar", "name": "alpha.sats", "avatar": "evil",
+	}), 14, testOwner)
+	if names.kvs["alpha.sats"][NAME_KEY_AVATAR] != avatar {
+		t.Fatalf("avatar changed without spending the name")
+	}
+	names.primary[testOwner] = ""
+	handle(handler, names, `{"p":"sns","op":"primary","name":"alpha.sats"}`, 15, testOwner)
+	if names.primary[testOwner] != "" {
+		t.Fatalf("primary name set without spending the name")
+	}
+}
+
+func TestBrc20HandlerDeploy(t *testing.T) {
+	names := newFakeNameService()
+	handler := &Brc20Handler{SelfMintHeight: 100}
+	deploy := func(content string, height int) bool {
+		count := len(names.tickers)
+		handle(handler, names, content, height, testOwner)
+		return len(names.tickers) > count
+	}
+
+	cases := []struct {
+		content string
+		height  int
+		ok      bool
+	}{
+		{`{"p":"brc-20","op":"deploy","tick":"ORDI","max":"21000000","lim":"1000"}`, 10, true},
+		{`{"p":"brc-20","op":"deploy","tick":"ordi","max":"1"}`, 11, false}, // 不区分大小写
+		{`{"p":"brc-20","op":"deploy","tick":"abc","max":"1"}`, 12, false},
+		{`{"p":"brc-20","op":"deploy","tick":"abcd","max":"0"}`, 13, false},
+		{`{"p":"brc-20","op":"deploy","tick":"abcd","max":"1.5","dec":"0"}`, 14, false},
+		{`{"p":"brc-20","op":"deploy","tick":"abcd","max":"1","dec":"19"}`, 15, false},
+		{`{"p":"brc-20","op":"deploy","tick":"abcd","max":"100","lim":"x"}`, 16, false},
+		{`{"p":"brc-20","op":"mint","tick":"abcd","amt":"1"}`, 17, false},
+		// 5字节ticker只能在生效以后self mint
+		{`{"p":"brc-20","op":"deploy","tick":"abcde","max":"0","self_mint":"true"}`, 99, false},
+		{`{"p":"brc-20","op":"deploy","tick":"abcde","max":"0"}`, 100, false},
+		{`{"p":"brc-20","op":"deploy","tick":"abcde","max":"0","self_mint":"true"}`, 100, true},
+		{`{"p":"brc-20","op":"deploy","tick":"abcd","max":"1.5","dec":"1"}`, 101, true},
+	}
+	for _, c := range cases {
+		if ok := deploy(c.content, c.height); ok != c.ok {
+			t.Errorf("%s at %d deployed %v, expected %v", c.content, c.height, ok, c.ok)
+		}
+	}
+
+	ticker := names.GetTicker("ORDI")
+	if ticker == nil || ticker.OriginalTicker != "ORDI" || ticker.Limit != "1000" ||
+		ticker.Decimal != BRC20_MAX_DECIMAL || ticker.Deployer != testOwner || ticker.Height != 10 {
+		t.Fatalf("ticker %+v", ticker)
+	}
+	if ticker := names.GetTicker("abcde"); ticker == nil || !ticker.SelfMint || ticker.Limit != "0" {
+		t.Fatalf("self mint ticker %+v", ticker)
+	}
+	// ticker不占用名字
+	if len(names.names) != 0 {
+		t.Fatalf("brc-20 deploy registered names")
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	names := newFakeNameService()
+	handler := &TextHandler{}
+	handle(handler, names, "alpha.sats", 10, testOwner)
+	if names.GetNameRegister("alpha.sats") == nil {
+		t.Fatalf("text name not registered")
+	}
+	handle(handler, names, "alpha.sats", 11, testOther)
+	if reg := names.GetNameRegister("alpha.sats"); reg.Owner != testOwner {
+		t.Fatalf("first registration replaced by %s", reg.Owner)
+	}
+}
+
+func TestBitmapHandler(t *testing.T) {
+	names := newFakeNameService()
+	handler := &BitmapHandler{}
+	cases := []struct {
+		content string
+		height  int
+		name    string
+	}{
+		{"100.bitmap", 200, "100.bitmap"},
+		{"200.bitmap", 200, "200.bitmap"},
+		{"201.bitmap", 200, ""},  // 高度不能超过铭文所在的区块
+		{"0100.bitmap", 200, ""}, // 前导0
+		{"0.bitmap", 200, "0.bitmap"},
+		{"abc.bitmap", 200, ""},
+		{"100.bitmap", 300, ""}, // 已经被注册
+	}
+	for _, c := range cases {
+		count := len(names.namespace)
+		env := handle(handler, names, c.content, c.height, testOwner)
+		registered := len(names.namespace) > count
+		if registered != (c.name != "") || (registered && !names.namespace[c.name]) {
+			t.Errorf("%s at %d registered %v, expected %s", c.content, c.height, registered, c.name)
+		}
+		name, ok := handler.ParseName(env)
+		if ok != bitmapRegexp.MatchString(c.content) || (ok && name != c.content) {
+			t.Errorf("ParseName(%s) returned %s %v", c.content, name, ok)
+		}
+	}
+}
+
+func TestBtcnameHandler(t *testing.T) {
+	names := newFakeNameService()
+	handler := &BtcnameHandler{}
+	// routing还没有实现，不修改任何数据
+	handle(handler, names, `{"p":"btcname","op":"routing","name":"alpha.sats","ord_handle":"x"}`, 10, testOwner)
+	handle(handler, names, "not json", 11, testOwner)
+	if len(names.names) != 0 || len(names.kvs) != 0 {
+		t.Fatalf("btcname routing changed names")
+	}
+}
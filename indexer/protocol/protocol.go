@@ -0,0 +1,197 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/ns"
+)
+
+// 解析后的铭文
+type Envelope struct {
+	Fields   map[int][]byte
+	Protocol string // 元协议，纯文本时为空
+	// 协议内容，有metaprotocol时是metadata转换成的json，否则是content
+	Content []byte
+	Nft     *common.Nft
+	// 有效的parent，在同一个交易中被花费
+	Parents []string
+}
+
+// 铭文所在的交易
+type TxContext struct {
+	Block      *common.Block
+	Tx         *common.Transaction
+	InputIndex int
+	Index      int    // 铭文在交易中的序号
+	Offset     uint64 // 铭文所在的聪在交易输出中的偏移
+}
+
+// 铭文所在的输出，返回接收地址和 txid:vout，铭文作为手续费时返回空
+func (t *TxContext) Receiver() (string, string) {
+	output := GetOutputAtOffset(t.Tx, t.Offset)
+	if output == nil {
+		return "", ""
+	}
+	return GetOutputAddress(output), fmt.Sprintf("%s:%d", t.Tx.Txid, output.N)
+}
+
+// 交易是否花费了 txid:vout
+func (t *TxContext) Spends(location string) bool {
+	for _, input := range t.Tx.Inputs {
+		if fmt.Sprintf("%s:%d", input.Txid, input.Vout) == location {
+			return true
+		}
+	}
+	return false
+}
+
+// 包含第offset个聪的输出，超出输出的总金额时返回nil
+func GetOutputAtOffset(tx *common.Transaction, offset uint64) *common.Output {
+	var end uint64
+	for _, output := range tx.Outputs {
+		end += uint64(output.Value)
+		if offset < end {
+			return output
+		}
+	}
+	return nil
+}
+
+func GetTotalOutputValue(tx *common.Transaction) uint64 {
+	var total uint64
+	for _, output := range tx.Outputs {
+		total += uint64(output.Value)
+	}
+	return total
+}
+
+func GetOutputAddress(output *common.Output) string {
+	if output.Address == nil || len(output.Address.Addresses) == 0 {
+		return ""
+	}
+	return output.Address.Addresses[0]
+}
+
+// 提供给协议的名字服务，名字的规范化和规则检查都在这里完成
+type NameService interface {
+	// 检查名字规则后注册，返回是否成功
+	RegisterName(name string, nft *common.Nft, tx *TxContext) bool
+	// 有自己规则的namespace，不检查sns的名字规则，只保证先注册先得
+	RegisterNamespaceName(name string, nft *common.Nft, tx *TxContext) bool
+	// 没有注册时返回nil
+	GetNameRegister(name string) *ns.NameRegister
+	// value为空时删除
+	SetKeyValues(name string, kvs map[string]string, nft *common.Nft)
+	SetPrimaryName(address, name string, nft *common.Nft)
+	// brc-20的ticker，和名字不在同一个namespace，先部署先得
+	DeployTicker(ticker *ns.Brc20Ticker) bool
+	// ticker不区分大小写，没有部署时返回nil
+	GetTicker(ticker string) *ns.Brc20Ticker
+}
+
+type Handler interface {
+	// 处理的元协议，和Envelope.Protocol相同
+	Protocol() string
+	Handle(env *Envelope, tx *TxContext, names NameService)
+}
+
+// 可选，铭文还在内存池中时就能知道要注册的名字
+type NameParser interface {
+	// 返回铭文要注册的名字，未经过名字规则的检查
+	ParseName(env *Envelope) (string, bool)
+}
+
+type registryEntry struct {
+	handler          Handler
+	activationHeight int
+}
+
+// 元协议到处理器的映射，同一个协议可以在不同高度使用不同的处理器
+type Registry struct {
+	mutex    sync.RWMutex
+	handlers map[string][]*registryEntry // 按生效高度排序
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string][]*registryEntry)}
+}
+
+// 包括所有内置的协议，从高度0开始生效
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, handler := range BuiltinHandlers() {
+		r.Register(handler, 0)
+	}
+	return r
+}
+
+// 从activationHeight开始使用handler，同一高度已有的处理器会被替换
+func (r *Registry) Register(handler Handler, activationHeight int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	protocol := handler.Protocol()
+	entries := r.handlers[protocol]
+	for i, entry := range entries {
+		if entry.activationHeight == activationHeight {
+			entries[i] = &registryEntry{handler: handler, activationHeight: activationHeight}
+			return
+		}
+	}
+	entries = append(entries, &registryEntry{handler: handler, activationHeight: activationHeight})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].activationHeight < entries[j].activationHeight
+	})
+	r.handlers[protocol] = entries
+}
+
+// 修改协议的生效高度，协议没有注册时返回false
+func (r *Registry) SetActivationHeight(protocol string, height int) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries, ok := r.handlers[protocol]
+	if !ok || len(entries) == 0 {
+		return false
+	}
+	// 只保留最新的处理器
+	handler := entries[len(entries)-1].handler
+	r.handlers[protocol] = []*registryEntry{{handler: handler, activationHeight: height}}
+	return true
+}
+
+func (r *Registry) Disable(protocol string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.handlers, protocol)
+}
+
+// 在height生效的处理器，没有时返回nil
+func (r *Registry) Get(protocol string, height int) Handler {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result Handler
+	for _, entry := range r.handlers[protocol] {
+		if entry.activationHeight > height {
+			break
+		}
+		result = entry.handler
+	}
+	return result
+}
+
+func (r *Registry) Protocols() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]string, 0, len(r.handlers))
+	for protocol := range r.handlers {
+		result = append(result, protocol)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"testing"
+)
+
+// 只用于区分注册的是哪个处理器
+type testHandler struct {
+	protocol string
+	version  int
+}
+
+func (p *testHandler) Protocol() string {
+	return p.protocol
+}
+
+func (p *testHandler) Handle(env *Envelope, tx *TxContext, names NameService) {}
+
+func getVersion(r *Registry, protocol string, height int) int {
+	handler := r.Get(protocol, height)
+	if handler == nil {
+		return 0
+	}
+	return handler.(*testHandler).version
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&testHandler{"test", 2}, 200)
+	r.Register(&testHandler{"test", 1}, 100)
+	r.Register(&testHandler{"other", 1}, 0)
+
+	cases := []struct {
+		height  int
+		version int
+	}{{0, 0}, {99, 0}, {100, 1}, {199, 1}, {200, 2}, {1000000, 2}}
+	for _, c := range cases {
+		if version := getVersion(r, "test", c.height); version != c.version {
+			t.Errorf("handler at %d is version %d, expected %d", c.height, version, c.version)
+		}
+	}
+
+	// 同一高度替换
+	r.Register(&testHandler{"test", 3}, 100)
+	if version := getVersion(r, "test", 150); version != 3 {
+		t.Fatalf("handler at 150 is version %d, expected 3", version)
+	}
+	if protocols := r.Protocols(); len(protocols) != 2 || protocols[0] != "other" || protocols[1] != "test" {
+		t.Fatalf("protocols %v", protocols)
+	}
+}
+
+func TestRegistrySetActivationHeight(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&testHandler{"test", 1}, 0)
+	r.Register(&testHandler{"test", 2}, 200)
+
+	// 只保留最新的处理器，从新的高度开始生效
+	if !r.SetActivationHeight("test", 300) {
+		t.Fatalf("SetActivationHeight failed")
+	}
+	if version := getVersion(r, "test", 250); version != 0 {
+		t.Fatalf("handler at 250 is version %d, expected none", version)
+	}
+	if version := getVersion(r, "test", 300); version != 2 {
+		t.Fatalf("handler at 300 is version %d, expected 2", version)
+	}
+	if r.SetActivationHeight("unknown", 0) {
+		t.Fatalf("SetActivationHeight of unknown protocol should fail")
+	}
+}
+
+func TestRegistryDisable(t *testing.T) {
+	r := NewDefaultRegistry()
+	for _, handler := range BuiltinHandlers() {
+		if r.Get(handler.Protocol(), 0) == nil {
+			t.Fatalf("builtin protocol '%s' not registered", handler.Protocol())
+		}
+	}
+
+	r.Disable(PROTOCOL_TEXT)
+	if r.Get(PROTOCOL_TEXT, 1000000) != nil {
+		t.Fatalf("disabled protocol still has a handler")
+	}
+	if r.SetActivationHeight(PROTOCOL_TEXT, 0) {
+		t.Fatalf("disabled protocol can't be enabled by SetActivationHeight")
+	}
+	// 重新注册
+	r.Register(&TextHandler{}, 10)
+	if r.Get(PROTOCOL_TEXT, 9) != nil || r.Get(PROTOCOL_TEXT, 10) == nil {
+		t.Fatalf("protocol registered again is not active from 10")
+	}
+}
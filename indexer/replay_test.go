@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"testing"
+
+	base_indexer "github.com/OLProtocol/ordx/indexer/base"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// 数据库在临时目录中，source为nil时不同步区块，测试直接调用处理函数
+func newTestIndexerMgr(t *testing.T, source base_indexer.BlockSource) *IndexerMgr {
+	instance = nil
+	mgr := NewIndexerMgr(t.TempDir()+"/", &chaincfg.RegressionNetParams)
+	t.Cleanup(func() { instance = nil })
+	mgr.Init()
+	if source != nil {
+		mgr.WithBlockSource(source)
+	}
+	t.Cleanup(func() { mgr.closeDB() })
+	return mgr
+}
+
+func checkNameHeight(t *testing.T, mgr *IndexerMgr, name string, height int) {
+	t.Helper()
+	reg := mgr.ns.GetNameRegisterInfo(name)
+	if height < 0 {
+		if reg != nil {
+			t.Fatalf("%s registered at %d, expected unregistered", name, reg.Nft.Base.BlockHeight)
+		}
+		return
+	}
+	if reg == nil {
+		t.Fatalf("%s not registered", name)
+	}
+	if int(reg.Nft.Base.BlockHeight) != height {
+		t.Fatalf("%s registered at %d, expected %d", name, reg.Nft.Base.BlockHeight, height)
+	}
+}
+
+// testdata/reorg: 区块1注册alpha.sats，区块2注册beta.sats。
+// 分叉reorg从区块2开始，区块2注册gamma.sats，区块4用另一个铭文注册beta.sats
+func TestReplayReorgFixture(t *testing.T) {
+	source, err := base_indexer.LoadFixture("testdata/reorg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := newTestIndexerMgr(t, source)
+	stop := make(chan struct{})
+
+	mgr.syncToChainTip(stop)
+	if mgr.compiling.GetHeight() != 3 {
+		t.Fatalf("synced to %d, expected 3", mgr.compiling.GetHeight())
+	}
+	checkNameHeight(t, mgr, "alpha.sats", 1)
+	checkNameHeight(t, mgr, "beta.sats", 2)
+	checkNameHeight(t, mgr, "gamma.sats", -1)
+
+	err = source.SwitchFork("reorg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一次同步发现reorg，重新加载数据库，第二次同步新的链
+	mgr.syncToChainTip(stop)
+	mgr.syncToChainTip(stop)
+	if mgr.compiling.GetHeight() != 4 {
+		t.Fatalf("synced to %d after reorg, expected 4", mgr.compiling.GetHeight())
+	}
+	checkNameHeight(t, mgr, "alpha.sats", 1)
+	checkNameHeight(t, mgr, "gamma.sats", 2)
+	checkNameHeight(t, mgr, "beta.sats", 4)
+}
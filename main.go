@@ -13,6 +13,14 @@ func init() {
 	if err != nil {
 		common.Log.Fatal(err)
 	}
+	err = g.InitEsplora()
+	if err != nil {
+		common.Log.Fatal(err)
+	}
+	err = g.InitZmq()
+	if err != nil {
+		common.Log.Fatal(err)
+	}
 	g.InitSigInt()
 }
 
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/OLProtocol/ordx/common"
 	"github.com/joho/godotenv"
@@ -51,6 +52,89 @@ func LoadConf(cfgPath string) (*Conf, error) {
 		}
 	}
 
+	fetchWorkers := 0
+	if value := conf["FETCH_WORKERS"]; value != "" {
+		fetchWorkers, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting FETCH_WORKERS to int")
+		}
+	}
+
+	blockPrefetch := 0
+	if value := conf["BLOCK_PREFETCH"]; value != "" {
+		blockPrefetch, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting BLOCK_PREFETCH to int")
+		}
+	}
+
+	esploraRetries := 0
+	if value := conf["ESPLORA_RETRIES"]; value != "" {
+		esploraRetries, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting ESPLORA_RETRIES to int")
+		}
+	}
+
+	esploraConcurrency := 0
+	if value := conf["ESPLORA_CONCURRENCY"]; value != "" {
+		esploraConcurrency, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting ESPLORA_CONCURRENCY to int")
+		}
+	}
+
+	mempoolEnable := conf["MEMPOOL_ENABLE"] == "true"
+	mempoolInterval := 0
+	if value := conf["MEMPOOL_POLL_INTERVAL"]; value != "" {
+		mempoolInterval, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting MEMPOOL_POLL_INTERVAL to int")
+		}
+	}
+
+	nameNormHeight := 0
+	if value := conf["NAME_NORMALIZATION_HEIGHT"]; value != "" {
+		nameNormHeight, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting NAME_NORMALIZATION_HEIGHT to int")
+		}
+	}
+
+	nameConfusableHeight := 0
+	if value := conf["NAME_CONFUSABLE_HEIGHT"]; value != "" {
+		nameConfusableHeight, err = strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalln("Error converting NAME_CONFUSABLE_HEIGHT to int")
+		}
+	}
+
+	nameLenRules, err := common.ParseNameLenRules(conf["NAME_LENGTH_RULES"])
+	if err != nil {
+		common.Log.Fatalf("Error parsing NAME_LENGTH_RULES. %v", err)
+	}
+
+	protocols := make([]*Protocol, 0)
+	for _, item := range strings.Split(conf["PROTOCOLS_ACTIVATION"], ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(item, ":")
+		height, err := strconv.Atoi(value)
+		if err != nil {
+			common.Log.Fatalf("Error parsing PROTOCOLS_ACTIVATION %s", item)
+		}
+		protocols = append(protocols, &Protocol{Name: name, ActivationHeight: height})
+	}
+	// 先设置生效高度，再关闭
+	for _, name := range strings.Split(conf["PROTOCOLS_DISABLE"], ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			protocols = append(protocols, &Protocol{Name: name, Disable: true})
+		}
+	}
+
 	maxIndexHeight, err := strconv.ParseInt(conf["MAX_INDEX_HEIGHT"], 10, 64)
 	if err != nil || maxIndexHeight <= 0 {
 		maxIndexHeight = -2
@@ -71,10 +155,27 @@ func LoadConf(cfgPath string) (*Conf, error) {
 		BitcoinRPCPass:  conf["BITCOIN_RPC_PASSWORD"],
 		BitcoinRPCPort:  bitcoinRPCPort,
 		BitcoinRPCHost:  conf["BITCOIN_RPC_HOST"],
+		BitcoinZmqAddr:  conf["BITCOIN_ZMQ_HASHBLOCK"],
+		EsploraUrl:      conf["ESPLORA_URL"],
+		EsploraRetries:  esploraRetries,
+		EsploraConcur:   esploraConcurrency,
 		DataDir:         dbDir,
 		LogLevel:        logLevel,
 		LogPath:         logPath,
 		PeriodFlushToDB: periodFlushToDB,
 		MaxIndexHeight:  maxIndexHeight,
+		FetchWorkers:    fetchWorkers,
+		BlockPrefetch:   blockPrefetch,
+		MempoolEnable:   mempoolEnable,
+		MempoolInterval: mempoolInterval,
+
+		NameNormalization:       conf["NAME_NORMALIZATION"],
+		NameNormalizationHeight: nameNormHeight,
+		NameConfusable:          conf["NAME_CONFUSABLE"],
+		NameConfusableHeight:    nameConfusableHeight,
+		NameConfusablesFile:     conf["NAME_CONFUSABLES_FILE"],
+		NameLenRules:            nameLenRules,
+		NameRestrictionFile:     conf["NAME_RESTRICTIONS_FILE"],
+		Protocols:               protocols,
 	}, nil
 }
@@ -1,6 +1,7 @@
 package conf
 
 import (
+	"github.com/OLProtocol/ordx/common"
 	"github.com/sirupsen/logrus"
 )
 
@@ -10,19 +11,39 @@ type Conf struct {
 	BitcoinRPCUser  string
 	BitcoinRPCPass  string
 	BitcoinRPCPort  int
+	BitcoinZmqAddr  string
+	EsploraUrl      string
+	EsploraRetries  int
+	EsploraConcur   int
 	DataDir         string
 	LogLevel        logrus.Level
 	LogPath         string
 	PeriodFlushToDB int
 	MaxIndexHeight  int64
+	FetchWorkers    int
+	BlockPrefetch   int
+	MempoolEnable   bool
+	MempoolInterval int
+	// 名字规则
+	NameNormalization       string
+	NameNormalizationHeight int
+	NameConfusable          string
+	NameConfusableHeight    int
+	NameConfusablesFile     string
+	NameLenRules            []*common.NameLenRule
+	NameRestrictionFile     string
+	Protocols               []*Protocol
 }
 
 type YamlConf struct {
-	Chain      string     `yaml:"chain"`
-	DB         DB         `yaml:"db"`
-	ShareRPC   ShareRPC   `yaml:"share_rpc"`
-	Log        Log        `yaml:"log"`
-	BasicIndex BasicIndex `yaml:"basic_index"`
+	Chain      string      `yaml:"chain"`
+	DB         DB          `yaml:"db"`
+	ShareRPC   ShareRPC    `yaml:"share_rpc"`
+	Log        Log         `yaml:"log"`
+	BasicIndex BasicIndex  `yaml:"basic_index"`
+	Mempool    Mempool     `yaml:"mempool"`
+	NameRules  NameRules   `yaml:"name_rules"`
+	Protocols  []*Protocol `yaml:"protocols"`
 }
 
 type DB struct {
@@ -31,6 +52,7 @@ type DB struct {
 
 type ShareRPC struct {
 	Bitcoin Bitcoin `yaml:"bitcoin"`
+	Esplora Esplora `yaml:"esplora"`
 }
 
 type Bitcoin struct {
@@ -38,6 +60,15 @@ type Bitcoin struct {
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
+	// bitcoind的zmqpubhashblock地址，例如 tcp://127.0.0.1:28336，为空时只轮询
+	ZmqPubHashBlock string `yaml:"zmqpubhashblock"`
+}
+
+// 设置url后，从esplora兼容的REST服务获取区块，不再需要全节点
+type Esplora struct {
+	Url         string `yaml:"url"`
+	Retries     int    `yaml:"retries"`
+	Concurrency int    `yaml:"concurrency"`
 }
 
 type Log struct {
@@ -45,7 +76,39 @@ type Log struct {
 	Path  string `yaml:"path"`
 }
 
+// 监控内存池中待确认的名字注册
+type Mempool struct {
+	Enable       bool `yaml:"enable"`
+	PollInterval int  `yaml:"poll_interval"` // 秒
+}
+
+// 名字的unicode规范化和形似名字的处理，为空时使用默认值
+type NameRules struct {
+	Normalization   string `yaml:"normalization"`    // none, nfc, nfkc
+	Confusable      string `yaml:"confusable"`       // off, flag, reject
+	ConfusablesFile string `yaml:"confusables_file"` // unicode confusables.txt 格式
+	// 规范化的生效高度，之前注册的名字只转小写
+	NormalizationHeight int `yaml:"normalization_height"`
+	// reject的生效高度，之前和flag一样
+	ConfusableHeight int `yaml:"confusable_height"`
+	// 在默认规则之上增加的长度规则
+	Length []*common.NameLenRule `yaml:"length"`
+	// 保留和屏蔽的名字，文件中是同样格式的列表
+	Restrictions     []*common.NameRestriction `yaml:"restrictions"`
+	RestrictionsFile string                    `yaml:"restrictions_file"`
+}
+
+// 内置元协议的开关和生效高度，没有配置的协议从高度0开始生效
+type Protocol struct {
+	Name             string   `yaml:"name"` // 纯文本用text
+	Disable          bool     `yaml:"disable"`
+	ActivationHeight int      `yaml:"activation_height"`
+	Chains           []string `yaml:"chains"` // 只在这些链上启用，为空时所有链都启用
+}
+
 type BasicIndex struct {
 	MaxIndexHeight  int64 `yaml:"max_index_height"`
 	PeriodFlushToDB int   `yaml:"period_flush_to_db"`
+	FetchWorkers    int   `yaml:"fetch_workers"`
+	BlockPrefetch   int   `yaml:"block_prefetch"`
 }
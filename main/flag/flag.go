@@ -2,7 +2,10 @@ package flag
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/OLProtocol/ordx/common"
 	mainCommon "github.com/OLProtocol/ordx/main/common"
@@ -14,6 +17,11 @@ func ParseCmdParams() {
 	init := flag.String("init", "", "generate config file in current dir")
 	env := flag.String("env", ".env", "env config file, default ./.env")
 	dbgc := flag.String("dbgc", "", "gc database log")
+	record := flag.String("record", "", "record blocks into a fixture dir")
+	recordRange := flag.String("range", "", "block range to record, ex: 100-120")
+	recordFork := flag.String("fork", "", "record the range as a fork of an existing fixture")
+	inspect := flag.String("inspect", "", "inspect a raw tx hex, or a file containing it, offline")
+	inspectHeight := flag.Int("height", 0, "block height of the inspected tx, default uses the latest rules")
 	help := flag.Bool("help", false, "show help.")
 	flag.Parse()
 
@@ -23,12 +31,20 @@ func ParseCmdParams() {
 		common.Log.Info("Usage: 'ordx-server -env default.yaml'")
 		common.Log.Info("Usage: 'ordx-server -env .env'")
 		common.Log.Info("Usage: 'ordx-server -dbgc ./db/mainnet'")
+		common.Log.Info("Usage: 'ordx-server -env .env -record ./fixture -range 100-120'")
+		common.Log.Info("Usage: 'ordx-server -env .env -record ./fixture -range 118-121 -fork reorg'")
+		common.Log.Info("Usage: 'ordx-server -env .env -inspect ./tx.hex -height 840000'")
 		common.Log.Info("Options:")
 		common.Log.Info("  run service ->")
 		common.Log.Info("    -init: init config file in current dir, default 'testnet'")
 		common.Log.Info("    -env: config file, default ./.env")
 		common.Log.Info("  run tool ->")
 		common.Log.Info("    -dbgc: gc database log, ex: ordx-server -dbgc ./db/mainnet")
+		common.Log.Info("    -record: record blocks from the configured block source into a fixture dir, used with -range")
+		common.Log.Info("    -range: block range to record, ex: 100-120")
+		common.Log.Info("    -fork: add the recorded range to an existing fixture as a fork with this name, ex: -fork reorg")
+		common.Log.Info("    -inspect: print the envelopes, protocols and name decisions of a raw tx hex, a file or '-' for stdin, without network or database")
+		common.Log.Info("    -height: block height used by -inspect, default uses the latest rules")
 		os.Exit(0)
 	}
 
@@ -56,6 +72,56 @@ func ParseCmdParams() {
 	if err != nil {
 		common.Log.Fatal(err)
 	}
+
+	if *record != "" {
+		err := recordFixture(*record, *recordFork, *recordRange)
+		if err != nil {
+			common.Log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if *inspect != "" {
+		// stdout只输出检查结果
+		common.Log.SetOutput(os.Stderr)
+		err := g.InspectTx(*inspect, *inspectHeight)
+		if err != nil {
+			common.Log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+}
+
+func recordFixture(dir, fork, blockRange string) error {
+	start, end, err := parseBlockRange(blockRange)
+	if err != nil {
+		return err
+	}
+	err = g.InitRpc()
+	if err != nil {
+		return err
+	}
+	err = g.InitEsplora()
+	if err != nil {
+		return err
+	}
+	return g.RecordFixture(dir, fork, start, end)
+}
+
+func parseBlockRange(blockRange string) (int, int, error) {
+	parts := strings.Split(blockRange, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid block range: %s", blockRange)
+	}
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid block range: %s", blockRange)
+	}
+	end, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid block range: %s", blockRange)
+	}
+	return start, end, nil
 }
 
 func generateDefaultCfg(chain string) error {
@@ -12,6 +12,8 @@ import (
 	"github.com/OLProtocol/ordx/common"
 	mainCommon "github.com/OLProtocol/ordx/main/common"
 	"github.com/OLProtocol/ordx/share/bitcoin_rpc"
+	"github.com/OLProtocol/ordx/share/bitcoin_zmq"
+	"github.com/OLProtocol/ordx/share/esplora"
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"github.com/sirupsen/logrus"
 )
@@ -125,6 +127,45 @@ func InitRpc() error {
 	return nil
 }
 
+func InitEsplora() error {
+	var url string
+	var retries int
+	var concurrency int
+	if mainCommon.YamlCfg != nil {
+		url = mainCommon.YamlCfg.ShareRPC.Esplora.Url
+		retries = mainCommon.YamlCfg.ShareRPC.Esplora.Retries
+		concurrency = mainCommon.YamlCfg.ShareRPC.Esplora.Concurrency
+	} else if mainCommon.Cfg != nil {
+		url = mainCommon.Cfg.EsploraUrl
+		retries = mainCommon.Cfg.EsploraRetries
+		concurrency = mainCommon.Cfg.EsploraConcur
+	}
+	if url == "" {
+		return nil
+	}
+	common.Log.WithFields(logrus.Fields{
+		"EsploraUrl":         url,
+		"EsploraRetries":     retries,
+		"EsploraConcurrency": concurrency,
+	}).Info("using esplora block source")
+	return esplora.InitEsplora(url, retries, concurrency)
+}
+
+func InitZmq() error {
+	addr := ""
+	if mainCommon.YamlCfg != nil {
+		addr = mainCommon.YamlCfg.ShareRPC.Bitcoin.ZmqPubHashBlock
+	} else if mainCommon.Cfg != nil {
+		addr = mainCommon.Cfg.BitcoinZmqAddr
+	}
+	if addr == "" {
+		common.Log.Info("zmqpubhashblock is not set, polling chain tip only")
+		return nil
+	}
+	common.Log.WithField("ZmqPubHashBlock", addr).Info("using zmq block notification")
+	return bitcoin_zmq.InitBitcoinZmq(addr)
+}
+
 func InitSigInt() {
 	count := 0
 	SigInt = make(chan os.Signal, 100)
@@ -151,4 +192,7 @@ func registSigIntFunc(callback func()) {
 }
 
 func ReleaseRes() {
+	if bitcoin_zmq.ShareBitcoinZmq != nil {
+		bitcoin_zmq.ShareBitcoinZmq.Close()
+	}
 }
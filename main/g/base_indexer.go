@@ -1,21 +1,102 @@
 package g
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	common "github.com/OLProtocol/ordx/common"
 	"github.com/OLProtocol/ordx/indexer"
+	base_indexer "github.com/OLProtocol/ordx/indexer/base"
+	"github.com/OLProtocol/ordx/indexer/mempool"
+	"github.com/OLProtocol/ordx/indexer/protocol"
 	mainCommon "github.com/OLProtocol/ordx/main/common"
+	mainConf "github.com/OLProtocol/ordx/main/conf"
+	"github.com/OLProtocol/ordx/share/bitcoin_zmq"
+	"github.com/OLProtocol/ordx/share/esplora"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
 )
 
 func InitBaseIndexer() error {
 	periodFlushToDB := int(0)
+	fetchWorkers := int(0)
+	blockPrefetch := int(0)
+	mempoolEnable := false
+	mempoolInterval := int(0)
 	if mainCommon.YamlCfg != nil {
 		periodFlushToDB = mainCommon.YamlCfg.BasicIndex.PeriodFlushToDB
+		fetchWorkers = mainCommon.YamlCfg.BasicIndex.FetchWorkers
+		blockPrefetch = mainCommon.YamlCfg.BasicIndex.BlockPrefetch
+		mempoolEnable = mainCommon.YamlCfg.Mempool.Enable
+		mempoolInterval = mainCommon.YamlCfg.Mempool.PollInterval
 	} else if mainCommon.Cfg != nil {
 		periodFlushToDB = mainCommon.Cfg.PeriodFlushToDB
+		fetchWorkers = mainCommon.Cfg.FetchWorkers
+		blockPrefetch = mainCommon.Cfg.BlockPrefetch
+		mempoolEnable = mainCommon.Cfg.MempoolEnable
+		mempoolInterval = mainCommon.Cfg.MempoolInterval
+	} else {
+		return fmt.Errorf("cfg is not set")
+	}
+
+	err := newIndexerMgr()
+	if err != nil {
+		return err
+	}
+
+	IndexerMgr.Init()
+
+	if periodFlushToDB != 0 {
+		common.Log.WithField("periodFlushToDB", periodFlushToDB).Info("using periodFlushToDB from conf")
+		IndexerMgr.WithPeriodFlushToDB(periodFlushToDB)
+	}
+	if fetchWorkers > 0 {
+		common.Log.WithField("fetchWorkers", fetchWorkers).Info("using fetchWorkers from conf")
+		IndexerMgr.WithFetchWorkers(fetchWorkers)
+	}
+	if blockPrefetch > 0 {
+		common.Log.WithField("blockPrefetch", blockPrefetch).Info("using blockPrefetch from conf")
+		IndexerMgr.WithBlockPrefetch(blockPrefetch)
+	}
+	if esplora.ShareEsplora != nil {
+		IndexerMgr.WithBlockSource(esplora.ShareEsplora)
+	}
+	if mempoolEnable {
+		common.Log.WithField("mempoolPollInterval", mempoolInterval).Info("mempool watcher enabled")
+		IndexerMgr.WithMempool(&mempool.RpcMempoolSource{}, time.Duration(mempoolInterval)*time.Second)
+	}
+	if bitcoin_zmq.ShareBitcoinZmq != nil {
+		IndexerMgr.WithBlockNotify(bitcoin_zmq.ShareBitcoinZmq.Notify())
+	}
+	return nil
+}
+
+// 根据配置创建IndexerMgr，设置名字规则和协议，不打开数据库
+func newIndexerMgr() error {
+	nameRules := mainConf.NameRules{}
+	var protocols []*mainConf.Protocol
+	dbDir := ""
+	if mainCommon.YamlCfg != nil {
+		nameRules = mainCommon.YamlCfg.NameRules
+		protocols = mainCommon.YamlCfg.Protocols
+		dbDir = mainCommon.YamlCfg.DB.Path
+	} else if mainCommon.Cfg != nil {
+		nameRules.Normalization = mainCommon.Cfg.NameNormalization
+		nameRules.NormalizationHeight = mainCommon.Cfg.NameNormalizationHeight
+		nameRules.Confusable = mainCommon.Cfg.NameConfusable
+		nameRules.ConfusableHeight = mainCommon.Cfg.NameConfusableHeight
+		nameRules.ConfusablesFile = mainCommon.Cfg.NameConfusablesFile
+		nameRules.Length = mainCommon.Cfg.NameLenRules
+		nameRules.RestrictionsFile = mainCommon.Cfg.NameRestrictionFile
+		protocols = mainCommon.Cfg.Protocols
+		dbDir = mainCommon.Cfg.DataDir
 	} else {
 		return fmt.Errorf("cfg is not set")
 	}
@@ -33,29 +114,74 @@ func InitBaseIndexer() error {
 	default:
 		return fmt.Errorf("unsupported chain: %s", chain)
 	}
-	dbDir := ""
-	if mainCommon.YamlCfg != nil {
-		dbDir = mainCommon.YamlCfg.DB.Path
-	} else if mainCommon.Cfg != nil {
-		dbDir = mainCommon.Cfg.DataDir
-	} else {
-		return fmt.Errorf("cfg is not set")
-	}
 	if !filepath.IsAbs(dbDir) {
 		dbDir = filepath.Clean(dbDir) + string(filepath.Separator)
 	}
 
 	IndexerMgr = indexer.NewIndexerMgr(dbDir, chainParam)
+	err = initNameRules(&nameRules)
+	if err != nil {
+		return err
+	}
+	return initProtocols(IndexerMgr.GetProtocolRegistry(), protocols, chain)
+}
 
-	IndexerMgr.Init()
+// 从配置的数据源录制区块，用于离线回放，fork不为空时作为分叉加入已经录制的目录
+func RecordFixture(dir, fork string, start, end int) error {
+	var source base_indexer.BlockSource = &base_indexer.RpcBlockSource{}
+	if esplora.ShareEsplora != nil {
+		source = esplora.ShareEsplora
+	}
+	if fork != "" {
+		return base_indexer.RecordFixtureFork(source, dir, fork, start, end)
+	}
+	return base_indexer.RecordFixture(source, dir, start, end)
+}
 
-	if periodFlushToDB != 0 {
-		common.Log.WithField("periodFlushToDB", periodFlushToDB).Info("using periodFlushToDB from conf")
-		IndexerMgr.WithPeriodFlushToDB(periodFlushToDB)
+// 离线检查交易中的铭文和名字注册，使用配置中的名字规则和协议，结果以json输出到stdout
+func InspectTx(input string, height int) error {
+	rawTx, err := readRawTx(input)
+	if err != nil {
+		return err
 	}
+	err = newIndexerMgr()
+	if err != nil {
+		return err
+	}
+	result, err := IndexerMgr.InspectTx(rawTx, height)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
 	return nil
 }
 
+// input是交易的hex，或者包含hex的文件，"-"时从stdin读取
+func readRawTx(input string) ([]byte, error) {
+	data := []byte(input)
+	if input == "-" {
+		var err error
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(input); err == nil {
+		data, err = os.ReadFile(input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rawTx, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction hex. %v", err)
+	}
+	return rawTx, nil
+}
+
 func RunBaseIndexer() error {
 	stopChan := make(chan bool)
 	cb := func() {
@@ -67,3 +193,104 @@ func RunBaseIndexer() error {
 	IndexerMgr.StartDaemon(stopChan)
 	return nil
 }
+
+func initNameRules(rules *mainConf.NameRules) error {
+	if rules.Normalization != "" {
+		if !common.IsValidNormForm(rules.Normalization) {
+			return fmt.Errorf("invalid name normalization %s", rules.Normalization)
+		}
+		common.Log.WithFields(logrus.Fields{
+			"normalization": rules.Normalization,
+			"height":        rules.NormalizationHeight,
+		}).Info("using name normalization from conf")
+		IndexerMgr.WithNameNormalization(rules.Normalization, rules.NormalizationHeight)
+	}
+
+	if rules.Confusable != "" || rules.ConfusablesFile != "" {
+		mode := rules.Confusable
+		if mode == "" {
+			mode = common.CONFUSABLE_FLAG
+		}
+		if !common.IsValidConfusableMode(mode) {
+			return fmt.Errorf("invalid name confusable mode %s", mode)
+		}
+		table := common.NewConfusableTable()
+		if rules.ConfusablesFile != "" {
+			err := table.Load(rules.ConfusablesFile)
+			if err != nil {
+				return fmt.Errorf("load confusables file failed. %v", err)
+			}
+		}
+		common.Log.WithFields(logrus.Fields{
+			"confusable": mode,
+			"height":     rules.ConfusableHeight,
+		}).Info("using name confusable mode from conf")
+		IndexerMgr.WithConfusables(mode, rules.ConfusableHeight, table)
+	}
+
+	if len(rules.Length) > 0 {
+		policy := common.NewNameLenPolicy()
+		for _, rule := range rules.Length {
+			err := policy.AddRule(rule)
+			if err != nil {
+				return err
+			}
+			common.Log.Infof("name length rule: namespace '%s' %d-%d %s from height %d",
+				rule.Namespace, rule.Min, rule.Max, rule.Unit, rule.ActivationHeight)
+		}
+		IndexerMgr.WithNameLenPolicy(policy)
+	}
+
+	restrictions := rules.Restrictions
+	if rules.RestrictionsFile != "" {
+		data, err := os.ReadFile(rules.RestrictionsFile)
+		if err != nil {
+			return fmt.Errorf("read name restrictions file failed. %v", err)
+		}
+		list := make([]*common.NameRestriction, 0)
+		err = yaml.Unmarshal(data, &list)
+		if err != nil {
+			return fmt.Errorf("invalid name restrictions file %s. %v", rules.RestrictionsFile, err)
+		}
+		restrictions = append(restrictions, list...)
+	}
+	if len(restrictions) > 0 {
+		// 依赖名字的规范化方式
+		err := IndexerMgr.WithNameRestrictions(restrictions)
+		if err != nil {
+			return err
+		}
+		common.Log.WithField("count", len(restrictions)).Info("using name restrictions from conf")
+	}
+	return nil
+}
+
+// 按配置修改registry中内置协议的生效高度，或者禁用协议
+func initProtocols(registry *protocol.Registry, protocols []*mainConf.Protocol, chain string) error {
+	for _, p := range protocols {
+		name := p.Name
+		if name == "text" {
+			name = protocol.PROTOCOL_TEXT
+		}
+		enabled := !p.Disable
+		if enabled && len(p.Chains) > 0 {
+			enabled = false
+			for _, c := range p.Chains {
+				if c == chain {
+					enabled = true
+					break
+				}
+			}
+		}
+		if !enabled {
+			common.Log.Infof("protocol '%s' disabled", p.Name)
+			registry.Disable(name)
+			continue
+		}
+		if !registry.SetActivationHeight(name, p.ActivationHeight) {
+			return fmt.Errorf("unknown protocol '%s'", p.Name)
+		}
+		common.Log.Infof("protocol '%s' enabled from height %d", p.Name, p.ActivationHeight)
+	}
+	return nil
+}
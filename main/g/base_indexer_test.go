@@ -0,0 +1,80 @@
+package g
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/OLProtocol/ordx/indexer/protocol"
+	mainConf "github.com/OLProtocol/ordx/main/conf"
+)
+
+func TestInitProtocols(t *testing.T) {
+	registry := protocol.NewDefaultRegistry()
+	err := initProtocols(registry, []*mainConf.Protocol{
+		{Name: "sns", ActivationHeight: 100},
+		{Name: "text", Disable: true},
+		{Name: "brc-20", Chains: []string{common.ChainMainnet}},
+		{Name: "bitmap", ActivationHeight: 200, Chains: []string{common.ChainTestnet4}},
+	}, common.ChainTestnet4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		protocol string
+		height   int
+		enabled  bool
+	}{
+		{protocol.PROTOCOL_SNS, 99, false},
+		{protocol.PROTOCOL_SNS, 100, true},
+		{protocol.PROTOCOL_TEXT, 1000, false},  // text对应纯文本
+		{protocol.PROTOCOL_BRC20, 1000, false}, // 只在主网启用
+		{protocol.PROTOCOL_BITMAP, 199, false},
+		{protocol.PROTOCOL_BITMAP, 200, true},
+		{protocol.PROTOCOL_BTCNAME, 0, true}, // 没有配置的协议不变
+	}
+	for _, c := range cases {
+		if enabled := registry.Get(c.protocol, c.height) != nil; enabled != c.enabled {
+			t.Errorf("protocol '%s' at %d enabled %v, expected %v", c.protocol, c.height, enabled, c.enabled)
+		}
+	}
+}
+
+func TestInitProtocolsUnknown(t *testing.T) {
+	registry := protocol.NewDefaultRegistry()
+	err := initProtocols(registry, []*mainConf.Protocol{{Name: "unknown", ActivationHeight: 1}}, common.ChainMainnet)
+	if err == nil {
+		t.Fatalf("unknown protocol should fail")
+	}
+	// 禁用未知的协议没有影响
+	err = initProtocols(registry, []*mainConf.Protocol{{Name: "unknown", Disable: true}}, common.ChainMainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// -inspect的参数可以是交易的hex，也可以是包含hex的文件
+func TestReadRawTx(t *testing.T) {
+	file := "../../indexer/testdata/inspect_tx.hex"
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromFile, err := readRawTx(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromHex, err := readRawTx(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromFile) == 0 || !bytes.Equal(fromFile, fromHex) {
+		t.Fatalf("file and hex input differ")
+	}
+	if _, err := readRawTx("not hex"); err == nil {
+		t.Fatalf("invalid hex accepted")
+	}
+}
@@ -0,0 +1,68 @@
+package g
+
+import (
+	"bytes"
+	"testing"
+
+	base_indexer "github.com/OLProtocol/ordx/indexer/base"
+	mainCommon "github.com/OLProtocol/ordx/main/common"
+	"github.com/OLProtocol/ordx/main/conf"
+	"github.com/OLProtocol/ordx/share/fake_bitcoind"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// 通过InitRpc连接fake bitcoind，区块数据和重试都走真实的rpc客户端
+func TestInitRpcWithFakeBitcoind(t *testing.T) {
+	node := fake_bitcoind.NewFakeBitcoind(&chaincfg.RegressionNetParams)
+	node.Start()
+	defer node.Close()
+	for i := 0; i < 3; i++ {
+		node.GenerateBlock()
+	}
+
+	host, port := node.Addr()
+	cfg, yamlCfg := mainCommon.Cfg, mainCommon.YamlCfg
+	defer func() { mainCommon.Cfg, mainCommon.YamlCfg = cfg, yamlCfg }()
+	mainCommon.YamlCfg = nil
+	mainCommon.Cfg = &conf.Conf{
+		BitcoinRPCHost: host,
+		BitcoinRPCPort: port,
+		BitcoinRPCUser: fake_bitcoind.RPC_USER,
+		BitcoinRPCPass: fake_bitcoind.RPC_PASSWORD,
+	}
+	err := InitRpc()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &base_indexer.RpcBlockSource{}
+	count, err := source.GetBlockCount()
+	if err != nil || count != 3 {
+		t.Fatalf("GetBlockCount returned %d %v, expected 3", count, err)
+	}
+
+	// 接下来两个请求失败，RpcBlockSource重试后成功
+	node.FailRequests(2)
+	hash, err := source.GetBlockHash(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := node.Block(2)
+	if hash != expected.BlockHash().String() {
+		t.Fatalf("block hash %s, expected %s", hash, expected.BlockHash())
+	}
+
+	raw, err := source.GetRawBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block wire.MsgBlock
+	err = block.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.BlockHash() != expected.BlockHash() {
+		t.Fatalf("raw block %s, expected %s", block.BlockHash(), expected.BlockHash())
+	}
+}
@@ -0,0 +1,159 @@
+package bitcoin_zmq
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/OLProtocol/ordx/common"
+	"github.com/lightninglabs/gozmq"
+)
+
+const (
+	TOPIC_HASHBLOCK = "hashblock"
+
+	// gozmq 在重连失败时会休眠这么久，也是多帧消息的读超时
+	recvTimeout = 5 * time.Second
+
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+)
+
+var ShareBitcoinZmq *BlockSubscriber
+
+// 订阅bitcoind的zmqpubhashblock，新区块到达时通知
+type BlockSubscriber struct {
+	addr     string
+	notifyCh chan string
+
+	mutex  sync.Mutex
+	conn   *gozmq.Conn
+	closed bool
+	quit   chan struct{}
+}
+
+func InitBitcoinZmq(addr string) error {
+	ShareBitcoinZmq = NewBlockSubscriber(addr)
+	return ShareBitcoinZmq.Start()
+}
+
+func NewBlockSubscriber(addr string) *BlockSubscriber {
+	return &BlockSubscriber{
+		addr: addr,
+		// 只需要知道有新区块，来不及处理的通知直接合并
+		notifyCh: make(chan string, 1),
+		quit:     make(chan struct{}),
+	}
+}
+
+func (p *BlockSubscriber) Start() error {
+	if p.addr == "" {
+		return errors.New("zmq address is empty")
+	}
+	go p.run()
+	return nil
+}
+
+// 新区块的hash，通道满时丢弃，接收方应该同步到最新高度，而不是依赖每个通知
+func (p *BlockSubscriber) Notify() <-chan string {
+	return p.notifyCh
+}
+
+func (p *BlockSubscriber) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.quit)
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+func (p *BlockSubscriber) isClosed() bool {
+	select {
+	case <-p.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *BlockSubscriber) run() {
+	interval := minReconnectInterval
+	for !p.isClosed() {
+		conn, err := gozmq.Subscribe(p.addr, []string{TOPIC_HASHBLOCK}, recvTimeout)
+		if err != nil {
+			common.Log.Warnf("BlockSubscriber: subscribe %s failed, retry in %v. %v", p.addr, interval, err)
+			select {
+			case <-p.quit:
+				return
+			case <-time.After(interval):
+			}
+			interval = nextReconnectInterval(interval)
+			continue
+		}
+
+		p.mutex.Lock()
+		if p.closed {
+			p.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		p.conn = conn
+		p.mutex.Unlock()
+
+		common.Log.Infof("BlockSubscriber: subscribed to %s on %s", TOPIC_HASHBLOCK, p.addr)
+		interval = minReconnectInterval
+		p.receive(conn)
+
+		p.mutex.Lock()
+		p.conn = nil
+		p.mutex.Unlock()
+		conn.Close()
+	}
+}
+
+// 每次失败后加倍，不超过maxReconnectInterval
+func nextReconnectInterval(interval time.Duration) time.Duration {
+	interval *= 2
+	if interval > maxReconnectInterval {
+		interval = maxReconnectInterval
+	}
+	return interval
+}
+
+// 阻塞读取消息，直到连接被关闭或者出现无法恢复的错误
+func (p *BlockSubscriber) receive(conn *gozmq.Conn) {
+	for {
+		msg, err := conn.Receive(nil)
+		if err != nil {
+			if err == io.EOF {
+				return
+			}
+			// gozmq自动重连后返回超时错误，可以继续读
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			common.Log.Warnf("BlockSubscriber: receive failed, resubscribe. %v", err)
+			return
+		}
+
+		// [topic, hash, sequence]
+		if len(msg) < 2 || string(msg[0]) != TOPIC_HASHBLOCK {
+			continue
+		}
+		hash := hex.EncodeToString(msg[1])
+		common.Log.Debugf("BlockSubscriber: new block %s", hash)
+
+		select {
+		case p.notifyCh <- hash:
+		default:
+		}
+	}
+}
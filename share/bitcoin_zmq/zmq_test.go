@@ -0,0 +1,199 @@
+package bitcoin_zmq
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// 只实现ZMTP 3.0 NULL机制的PUB端，足够gozmq订阅
+type fakePublisher struct {
+	listener net.Listener
+	conns    chan net.Conn
+}
+
+func listenPublisher(t *testing.T, addr string) *fakePublisher {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePublisher{listener: listener, conns: make(chan net.Conn, 4)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if err := handshake(conn); err != nil {
+				conn.Close()
+				continue
+			}
+			p.conns <- conn
+		}
+	}()
+	return p
+}
+
+func (p *fakePublisher) accept(t *testing.T) net.Conn {
+	t.Helper()
+	select {
+	case conn := <-p.conns:
+		return conn
+	case <-time.After(10 * time.Second):
+		t.Fatal("subscriber not connected")
+		return nil
+	}
+}
+
+func (p *fakePublisher) close() {
+	p.listener.Close()
+}
+
+func handshake(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	greeting := make([]byte, 64)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return err
+	}
+	reply := make([]byte, 64)
+	reply[0], reply[9], reply[10] = 0xff, 0x7f, 3
+	copy(reply[12:], "NULL")
+	if _, err := conn.Write(reply); err != nil {
+		return err
+	}
+
+	// READY
+	if _, err := readFrame(conn); err != nil {
+		return err
+	}
+	ready := append([]byte{5}, "READY"...)
+	ready = append(ready, 11)
+	ready = append(ready, "Socket-Type"...)
+	ready = append(ready, 0, 0, 0, 3)
+	ready = append(ready, "PUB"...)
+	if _, err := conn.Write(append([]byte{4, byte(len(ready))}, ready...)); err != nil {
+		return err
+	}
+
+	// 订阅消息
+	_, err := readFrame(conn)
+	return err
+}
+
+func readFrame(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	body := make([]byte, header[1])
+	_, err := io.ReadFull(conn, body)
+	return body, err
+}
+
+func publishHashBlock(t *testing.T, conn net.Conn, hash []byte) {
+	t.Helper()
+	var msg bytes.Buffer
+	msg.Write([]byte{1, byte(len(TOPIC_HASHBLOCK))})
+	msg.WriteString(TOPIC_HASHBLOCK)
+	msg.Write([]byte{1, byte(len(hash))})
+	msg.Write(hash)
+	msg.Write([]byte{0, 4, 0, 0, 0, 0})
+	if _, err := conn.Write(msg.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectNotify(t *testing.T, sub *BlockSubscriber, hash []byte) {
+	t.Helper()
+	select {
+	case got := <-sub.Notify():
+		if got != hex.EncodeToString(hash) {
+			t.Fatalf("got %s, want %x", got, hash)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no notify for %x", hash)
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func TestBlockSubscriberReconnect(t *testing.T) {
+	addr := freeAddr(t)
+	sub := NewBlockSubscriber("tcp://" + addr)
+	if err := sub.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// 发布者还没有启动，订阅失败后等待重试
+	time.Sleep(100 * time.Millisecond)
+	pub := listenPublisher(t, addr)
+	defer pub.close()
+
+	conn := pub.accept(t)
+	hash1 := bytes.Repeat([]byte{1}, 32)
+	publishHashBlock(t, conn, hash1)
+	expectNotify(t, sub, hash1)
+
+	// 连接断开后重新订阅
+	conn.Close()
+	conn = pub.accept(t)
+	defer conn.Close()
+	hash2 := bytes.Repeat([]byte{2}, 32)
+	publishHashBlock(t, conn, hash2)
+	expectNotify(t, sub, hash2)
+}
+
+func TestBlockSubscriberMergeNotify(t *testing.T) {
+	addr := freeAddr(t)
+	pub := listenPublisher(t, addr)
+	defer pub.close()
+	sub := NewBlockSubscriber("tcp://" + addr)
+	if err := sub.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	conn := pub.accept(t)
+	defer conn.Close()
+	// 没有及时处理的通知只保留第一个
+	for i := byte(1); i <= 3; i++ {
+		publishHashBlock(t, conn, bytes.Repeat([]byte{i}, 32))
+	}
+	time.Sleep(200 * time.Millisecond)
+	expectNotify(t, sub, bytes.Repeat([]byte{1}, 32))
+	select {
+	case got := <-sub.Notify():
+		t.Fatalf("unexpected notify %s", got)
+	default:
+	}
+}
+
+func TestNextReconnectInterval(t *testing.T) {
+	interval := minReconnectInterval
+	for i := 0; i < 10; i++ {
+		next := nextReconnectInterval(interval)
+		if next < interval || next > maxReconnectInterval {
+			t.Fatalf("invalid interval %v after %v", next, interval)
+		}
+		interval = next
+	}
+	if interval != maxReconnectInterval {
+		t.Fatalf("interval %v not capped at %v", interval, maxReconnectInterval)
+	}
+}
@@ -0,0 +1,119 @@
+package esplora
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OLProtocol/ordx/common"
+)
+
+const (
+	DEFAULT_RETRIES     = 10
+	DEFAULT_CONCURRENCY = 4
+)
+
+var ShareEsplora *Client
+
+// 兼容esplora REST接口的区块来源，例如mempool.space或者自建的esplora
+type Client struct {
+	baseUrl string
+	retries int
+	client  *http.Client
+	// 第n次重试之前等待n倍的retryDelay
+	retryDelay time.Duration
+	// 限制同时发出的请求数
+	slots chan struct{}
+}
+
+func InitEsplora(baseUrl string, retries, concurrency int) error {
+	var err error
+	ShareEsplora, err = NewClient(baseUrl, retries, concurrency)
+	return err
+}
+
+func NewClient(baseUrl string, retries, concurrency int) (*Client, error) {
+	if _, err := url.Parse(baseUrl); err != nil || baseUrl == "" {
+		return nil, fmt.Errorf("invalid esplora url: %s", baseUrl)
+	}
+	if retries <= 0 {
+		retries = DEFAULT_RETRIES
+	}
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+	return &Client{
+		baseUrl:    baseUrl,
+		retries:    retries,
+		client:     &http.Client{Timeout: 5 * time.Minute},
+		retryDelay: time.Second,
+		slots:      make(chan struct{}, concurrency),
+	}, nil
+}
+
+func (c *Client) GetBlockCount() (uint64, error) {
+	data, err := c.get("blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func (c *Client) GetBlockHash(height uint64) (string, error) {
+	data, err := c.get("block-height/" + strconv.FormatUint(height, 10))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (c *Client) GetRawBlock(blockHash string) ([]byte, error) {
+	return c.get("block/" + blockHash + "/raw")
+}
+
+func (c *Client) get(path string) ([]byte, error) {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+
+	p, err := url.JoinPath(c.baseUrl, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for n := 1; n <= c.retries; n++ {
+		var retry bool
+		data, retry, err = c.request(p)
+		if err == nil || !retry {
+			break
+		}
+		if n < c.retries {
+			common.Log.Infof("esplora request %s failed, try again ... %v", path, err)
+			time.Sleep(time.Duration(n) * c.retryDelay)
+		}
+	}
+	return data, err
+}
+
+// 连接失败，服务端错误和限流可以重试，其他错误（比如404）直接返回
+func (c *Client) request(p string) ([]byte, bool, error) {
+	resp, err := c.client.Get(p)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, fmt.Errorf("esplora: GET %s statusCode: %d, error: %s", p, resp.StatusCode, string(data))
+	}
+	return data, false, nil
+}
@@ -0,0 +1,198 @@
+package esplora
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟esplora的REST接口，failures中的路径先返回对应的状态码
+type fakeEsplora struct {
+	mutex     sync.Mutex
+	responses map[string][]byte
+	failures  map[string][]int
+	requests  map[string]int
+	inflight  int
+	maxFlight int
+	delay     time.Duration
+}
+
+func newFakeEsplora() *fakeEsplora {
+	return &fakeEsplora{
+		responses: map[string][]byte{
+			"/blocks/tip/height":   []byte("840000\n"),
+			"/block-height/840000": []byte("000000000000000000032bd45ecbf3f8b1d37b8d4b5ec0b67ee9e70e3bfcb2a4"),
+			"/block/0000abcd/raw":  {0x01, 0x02, 0x03},
+			"/api/block-height/1":  []byte("0000abcd"),
+		},
+		failures: make(map[string][]int),
+		requests: make(map[string]int),
+	}
+}
+
+func (f *fakeEsplora) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests[r.URL.Path]++
+	f.inflight++
+	if f.inflight > f.maxFlight {
+		f.maxFlight = f.inflight
+	}
+	var status int
+	if failures := f.failures[r.URL.Path]; len(failures) > 0 {
+		status = failures[0]
+		f.failures[r.URL.Path] = failures[1:]
+	}
+	data, ok := f.responses[r.URL.Path]
+	delay := f.delay
+	f.mutex.Unlock()
+
+	time.Sleep(delay)
+	f.mutex.Lock()
+	f.inflight--
+	f.mutex.Unlock()
+
+	switch {
+	case status != 0:
+		w.WriteHeader(status)
+		w.Write([]byte("failure"))
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Block not found"))
+	default:
+		w.Write(data)
+	}
+}
+
+func (f *fakeEsplora) count(path string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.requests[path]
+}
+
+func newTestClient(t *testing.T, f *fakeEsplora, path string, retries, concurrency int) *Client {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	c, err := NewClient(server.URL+path, retries, concurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.retryDelay = time.Millisecond
+	return c
+}
+
+func TestClientBlocks(t *testing.T) {
+	f := newFakeEsplora()
+	c := newTestClient(t, f, "", 3, 2)
+
+	count, err := c.GetBlockCount()
+	if err != nil || count != 840000 {
+		t.Fatalf("block count %d %v", count, err)
+	}
+	hash, err := c.GetBlockHash(840000)
+	if err != nil || hash != "000000000000000000032bd45ecbf3f8b1d37b8d4b5ec0b67ee9e70e3bfcb2a4" {
+		t.Fatalf("block hash %s %v", hash, err)
+	}
+	data, err := c.GetRawBlock("0000abcd")
+	if err != nil || !bytes.Equal(data, []byte{0x01, 0x02, 0x03}) {
+		t.Fatalf("raw block %x %v", data, err)
+	}
+
+	// baseUrl可以包含路径
+	c = newTestClient(t, f, "/api", 3, 2)
+	hash, err = c.GetBlockHash(1)
+	if err != nil || hash != "0000abcd" {
+		t.Fatalf("block hash with path %s %v", hash, err)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	f := newFakeEsplora()
+	f.failures["/blocks/tip/height"] = []int{http.StatusInternalServerError, http.StatusTooManyRequests}
+	c := newTestClient(t, f, "", 3, 2)
+
+	// 服务端错误和限流重试
+	count, err := c.GetBlockCount()
+	if err != nil || count != 840000 {
+		t.Fatalf("block count %d %v", count, err)
+	}
+	if n := f.count("/blocks/tip/height"); n != 3 {
+		t.Fatalf("%d requests, expected 3", n)
+	}
+
+	// 重试次数用完
+	f.failures["/block/0000abcd/raw"] = []int{502, 502, 502, 502}
+	if _, err := c.GetRawBlock("0000abcd"); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if n := f.count("/block/0000abcd/raw"); n != 3 {
+		t.Fatalf("%d requests, expected 3", n)
+	}
+
+	// 404等错误不重试
+	if _, err := c.GetRawBlock("0000ffff"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected 404 error, got %v", err)
+	}
+	if n := f.count("/block/0000ffff/raw"); n != 1 {
+		t.Fatalf("%d requests for missing block, expected 1", n)
+	}
+
+	// 返回的高度格式错误
+	f.responses["/blocks/tip/height"] = []byte("tip")
+	if _, err := c.GetBlockCount(); err == nil {
+		t.Fatalf("invalid height accepted")
+	}
+}
+
+func TestClientConnectionError(t *testing.T) {
+	server := httptest.NewServer(newFakeEsplora())
+	url := server.URL
+	server.Close()
+
+	c, err := NewClient(url, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.retryDelay = time.Millisecond
+	if _, err := c.GetBlockCount(); err == nil {
+		t.Fatalf("expected connection error")
+	}
+}
+
+func TestClientConcurrency(t *testing.T) {
+	f := newFakeEsplora()
+	f.delay = 20 * time.Millisecond
+	c := newTestClient(t, f, "", 1, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.GetBlockCount()
+		}()
+	}
+	wg.Wait()
+	if f.maxFlight > 2 {
+		t.Fatalf("%d concurrent requests, expected at most 2", f.maxFlight)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient("", 0, 0); err == nil {
+		t.Fatalf("empty url accepted")
+	}
+	if _, err := NewClient("http://[::1", 0, 0); err == nil {
+		t.Fatalf("invalid url accepted")
+	}
+	c, err := NewClient("http://localhost", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.retries != DEFAULT_RETRIES || cap(c.slots) != DEFAULT_CONCURRENCY {
+		t.Fatalf("defaults %d %d", c.retries, cap(c.slots))
+	}
+}
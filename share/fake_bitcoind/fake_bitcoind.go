@@ -0,0 +1,355 @@
+package fake_bitcoind
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	RPC_USER     = "user"
+	RPC_PASSWORD = "password"
+
+	// bitcoind的错误码
+	RPC_MISC_ERROR             = -1
+	RPC_METHOD_NOT_FOUND       = -32601
+	RPC_INVALID_PARAMETER      = -8
+	RPC_INVALID_ADDRESS_OR_KEY = -5
+)
+
+// 进程内的bitcoind json-rpc服务，链数据在内存中，测试可以随时出块、回滚和注入错误
+type FakeBitcoind struct {
+	mutex   sync.RWMutex
+	params  *chaincfg.Params
+	blocks  []*wire.MsgBlock // index is height
+	txs     map[chainhash.Hash]*wire.MsgTx
+	mempool map[chainhash.Hash]*wire.MsgTx
+	// 接下来这么多个请求返回http 500，用于测试重试
+	failCount int
+
+	server *httptest.Server
+}
+
+func NewFakeBitcoind(params *chaincfg.Params) *FakeBitcoind {
+	p := &FakeBitcoind{
+		params:  params,
+		blocks:  make([]*wire.MsgBlock, 0),
+		txs:     make(map[chainhash.Hash]*wire.MsgTx),
+		mempool: make(map[chainhash.Hash]*wire.MsgTx),
+	}
+	p.addBlock(params.GenesisBlock)
+	return p
+}
+
+// 在本地随机端口上启动服务
+func (p *FakeBitcoind) Start() {
+	p.server = httptest.NewServer(http.HandlerFunc(p.serveHTTP))
+}
+
+func (p *FakeBitcoind) Close() {
+	if p.server != nil {
+		p.server.Close()
+	}
+}
+
+// 用于 bitcoin_rpc.InitBitconRpc
+func (p *FakeBitcoind) Addr() (string, int) {
+	addr := p.server.Listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func (p *FakeBitcoind) URL() string {
+	return p.server.URL
+}
+
+func (p *FakeBitcoind) FailRequests(n int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.failCount = n
+}
+
+func (p *FakeBitcoind) Height() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return len(p.blocks) - 1
+}
+
+func (p *FakeBitcoind) Block(height int) *wire.MsgBlock {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if height < 0 || height >= len(p.blocks) {
+		return nil
+	}
+	return p.blocks[height]
+}
+
+// 在链顶端出一个块，包含coinbase和给定的交易，这些交易从内存池中移除
+func (p *FakeBitcoind) GenerateBlock(txs ...*wire.MsgTx) *wire.MsgBlock {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	height := len(p.blocks)
+	prev := p.blocks[height-1]
+
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	heightScript, _ := txscript.NewScriptBuilder().AddInt64(int64(height)).Script()
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex),
+		SignatureScript:  heightScript,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(50*btcutil.SatoshiPerBitcoin, []byte{txscript.OP_TRUE}))
+
+	block := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:   4,
+			PrevBlock: prev.BlockHash(),
+			Timestamp: prev.Header.Timestamp.Add(10 * time.Minute),
+			Bits:      p.params.PowLimitBits,
+		},
+	}
+	block.AddTransaction(coinbase)
+	for _, tx := range txs {
+		block.AddTransaction(tx)
+	}
+
+	utilTxs := make([]*btcutil.Tx, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		utilTxs[i] = btcutil.NewTx(tx)
+	}
+	block.Header.MerkleRoot = blockchain.CalcMerkleRoot(utilTxs, false)
+
+	p.addBlock(block)
+	return block
+}
+
+func (p *FakeBitcoind) addBlock(block *wire.MsgBlock) {
+	p.blocks = append(p.blocks, block)
+	for _, tx := range block.Transactions {
+		hash := tx.TxHash()
+		p.txs[hash] = tx
+		delete(p.mempool, hash)
+	}
+}
+
+// 回滚到height，之后出的块形成新的分叉。和bitcoind一样，被回滚区块中除coinbase以外的交易回到内存池
+func (p *FakeBitcoind) Reorg(height int) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if height < 0 || height >= len(p.blocks) {
+		return fmt.Errorf("invalid reorg height %d, chain height %d", height, len(p.blocks)-1)
+	}
+	for _, block := range p.blocks[height+1:] {
+		for i, tx := range block.Transactions {
+			hash := tx.TxHash()
+			delete(p.txs, hash)
+			if i > 0 {
+				p.mempool[hash] = tx
+			}
+		}
+	}
+	p.blocks = p.blocks[:height+1]
+	return nil
+}
+
+func (p *FakeBitcoind) AddMempoolTx(tx *wire.MsgTx) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.mempool[tx.TxHash()] = tx
+}
+
+func (p *FakeBitcoind) RemoveMempoolTx(hash chainhash.Hash) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.mempool, hash)
+}
+
+type rpcRequest struct {
+	Id     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Id     interface{} `json:"id"`
+	Result interface{} `json:"result"`
+	Err    *rpcError   `json:"error"`
+}
+
+func (p *FakeBitcoind) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	if p.failCount > 0 {
+		p.failCount--
+		p.mutex.Unlock()
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+	p.mutex.Unlock()
+
+	user, password, ok := r.BasicAuth()
+	if !ok || user != RPC_USER || password != RPC_PASSWORD {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req rpcRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, rpcErr := p.handle(&req)
+	resp := rpcResponse{Id: req.Id, Result: result, Err: rpcErr}
+	w.Header().Set("Content-Type", "application/json")
+	if rpcErr != nil {
+		// bitcoind对错误返回500
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(&resp)
+}
+
+func (p *FakeBitcoind) handle(req *rpcRequest) (interface{}, *rpcError) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	switch req.Method {
+	case "getblockcount":
+		return len(p.blocks) - 1, nil
+
+	case "getblockhash":
+		var height int
+		if err := getParam(req, 0, &height); err != nil {
+			return nil, err
+		}
+		if height < 0 || height >= len(p.blocks) {
+			return nil, &rpcError{Code: RPC_INVALID_PARAMETER, Message: "Block height out of range"}
+		}
+		return p.blocks[height].BlockHash().String(), nil
+
+	case "getblock":
+		var hash string
+		if err := getParam(req, 0, &hash); err != nil {
+			return nil, err
+		}
+		verbosity, rpcErr := getVerbosity(req, 1, 1)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if verbosity != 0 {
+			return nil, &rpcError{Code: RPC_INVALID_PARAMETER, Message: "only verbosity 0 is supported"}
+		}
+		for _, block := range p.blocks {
+			if block.BlockHash().String() == hash {
+				return serializeHex(block.Serialize)
+			}
+		}
+		return nil, &rpcError{Code: RPC_INVALID_ADDRESS_OR_KEY, Message: "Block not found"}
+
+	case "getrawtransaction":
+		var txid string
+		if err := getParam(req, 0, &txid); err != nil {
+			return nil, err
+		}
+		verbosity, rpcErr := getVerbosity(req, 1, 0)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		verbose := verbosity != 0
+		hash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, &rpcError{Code: RPC_INVALID_PARAMETER, Message: err.Error()}
+		}
+		tx, ok := p.mempool[*hash]
+		if !ok {
+			tx, ok = p.txs[*hash]
+		}
+		if !ok {
+			return nil, &rpcError{Code: RPC_INVALID_ADDRESS_OR_KEY, Message: "No such mempool or blockchain transaction"}
+		}
+		txHex, rpcErr := serializeHex(tx.Serialize)
+		if rpcErr != nil || !verbose {
+			return txHex, rpcErr
+		}
+		return map[string]interface{}{
+			"txid": tx.TxHash().String(),
+			"hash": tx.WitnessHash().String(),
+			"hex":  txHex,
+		}, nil
+
+	case "getrawmempool":
+		txids := make([]string, 0, len(p.mempool))
+		for hash := range p.mempool {
+			txids = append(txids, hash.String())
+		}
+		return txids, nil
+
+	default:
+		return nil, &rpcError{Code: RPC_METHOD_NOT_FOUND, Message: "Method not found"}
+	}
+}
+
+func getParam(req *rpcRequest, i int, value interface{}) *rpcError {
+	if i >= len(req.Params) {
+		return &rpcError{Code: RPC_MISC_ERROR, Message: req.Method + " needs param " + strconv.Itoa(i)}
+	}
+	err := json.Unmarshal(req.Params[i], value)
+	if err != nil {
+		return &rpcError{Code: RPC_INVALID_PARAMETER, Message: err.Error()}
+	}
+	return nil
+}
+
+// bitcoind 的verbosity/verbose参数同时接受bool和数字，true相当于1
+func getVerbosity(req *rpcRequest, i int, defaultValue int) (int, *rpcError) {
+	if i >= len(req.Params) {
+		return defaultValue, nil
+	}
+	var v interface{}
+	if err := getParam(req, i, &v); err != nil {
+		return 0, err
+	}
+	switch value := v.(type) {
+	case nil:
+		return defaultValue, nil
+	case bool:
+		if value {
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		return int(value), nil
+	default:
+		return 0, &rpcError{Code: RPC_INVALID_PARAMETER, Message: "verbosity should be bool or number"}
+	}
+}
+
+func serializeHex(serialize func(w io.Writer) error) (string, *rpcError) {
+	var buf bytes.Buffer
+	err := serialize(&buf)
+	if err != nil {
+		return "", &rpcError{Code: RPC_MISC_ERROR, Message: err.Error()}
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,142 @@
+package fake_bitcoind
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func call(t *testing.T, p *FakeBitcoind, method string, params ...interface{}) (json.RawMessage, *rpcError) {
+	t.Helper()
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, _ := json.Marshal(map[string]interface{}{"id": 1, "method": method, "params": params})
+	req, _ := http.NewRequest("POST", p.URL(), bytes.NewReader(body))
+	req.SetBasicAuth(RPC_USER, RPC_PASSWORD)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+		Err    *rpcError       `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatalf("%s: invalid response, %v", method, err)
+	}
+	return result.Result, result.Err
+}
+
+func testTx(seed byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{seed}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	return tx
+}
+
+func TestGetBlockVerbosity(t *testing.T) {
+	p := NewFakeBitcoind(&chaincfg.RegressionNetParams)
+	p.Start()
+	defer p.Close()
+	block := p.GenerateBlock(testTx(1))
+	hash := block.BlockHash().String()
+
+	var buf bytes.Buffer
+	block.Serialize(&buf)
+	expected := hex.EncodeToString(buf.Bytes())
+
+	// bitcoind 对verbosity接受数字和bool
+	for _, verbosity := range []interface{}{0, false} {
+		result, rpcErr := call(t, p, "getblock", hash, verbosity)
+		if rpcErr != nil {
+			t.Fatalf("getblock %v: %s", verbosity, rpcErr.Message)
+		}
+		var raw string
+		json.Unmarshal(result, &raw)
+		if raw != expected {
+			t.Fatalf("getblock %v returned a different block", verbosity)
+		}
+	}
+	// 只支持原始数据，默认verbosity是1
+	for _, params := range [][]interface{}{{hash}, {hash, 1}, {hash, true}, {hash, "0"}} {
+		if _, rpcErr := call(t, p, "getblock", params...); rpcErr == nil {
+			t.Fatalf("getblock %v should fail", params)
+		}
+	}
+}
+
+func TestReorgReturnsTxsToMempool(t *testing.T) {
+	p := NewFakeBitcoind(&chaincfg.RegressionNetParams)
+	p.Start()
+	defer p.Close()
+
+	tx1 := testTx(1)
+	tx2 := testTx(2)
+	p.AddMempoolTx(tx1)
+	p.AddMempoolTx(tx2)
+	p.GenerateBlock()
+	block := p.GenerateBlock(tx1, tx2)
+
+	result, _ := call(t, p, "getrawmempool")
+	var txids []string
+	json.Unmarshal(result, &txids)
+	if len(txids) != 0 {
+		t.Fatalf("mempool %v, expected empty after mined", txids)
+	}
+
+	err := p.Reorg(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ = call(t, p, "getrawmempool")
+	json.Unmarshal(result, &txids)
+	if len(txids) != 2 {
+		t.Fatalf("mempool %v, expected the 2 disconnected txs", txids)
+	}
+	// coinbase 不回到内存池，也不能再查询
+	coinbase := block.Transactions[0].TxHash().String()
+	if _, rpcErr := call(t, p, "getrawtransaction", coinbase); rpcErr == nil {
+		t.Fatalf("disconnected coinbase %s still exists", coinbase)
+	}
+	if _, rpcErr := call(t, p, "getrawtransaction", tx1.TxHash().String(), true); rpcErr != nil {
+		t.Fatalf("disconnected tx not in mempool, %s", rpcErr.Message)
+	}
+
+	// 新的分叉再次打包
+	p.GenerateBlock(tx2)
+	result, _ = call(t, p, "getrawmempool")
+	json.Unmarshal(result, &txids)
+	if len(txids) != 1 || txids[0] != tx1.TxHash().String() {
+		t.Fatalf("mempool %v, expected only %s", txids, tx1.TxHash())
+	}
+}
+
+func TestFailRequests(t *testing.T) {
+	p := NewFakeBitcoind(&chaincfg.RegressionNetParams)
+	p.Start()
+	defer p.Close()
+	p.FailRequests(1)
+
+	resp, err := http.Post(p.URL(), "application/json", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status %d, expected injected failure", resp.StatusCode)
+	}
+	result, rpcErr := call(t, p, "getblockcount")
+	if rpcErr != nil || string(result) != "0" {
+		t.Fatalf("getblockcount returned %s %v after failure", result, rpcErr)
+	}
+}